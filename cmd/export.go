@@ -29,6 +29,7 @@ import (
 	"github.com/fimreal/docker-exporter/dockercli"
 	"github.com/fimreal/goutils/ezap"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // exportCmd represents the export command
@@ -49,8 +50,59 @@ replicating setups or troubleshooting issues.`,
 		}
 
 		output, _ := cmd.Flags().GetString("output-dir")
-		pretty, _ := cmd.Flags().GetBool("pretty")
-		dump := dockercli.ParseContainers(cjson, format, pretty)
+		withData, _ := cmd.Flags().GetBool("with-data")
+		if withData && output == "" {
+			ezap.Error("--with-data requires an output directory, set it with -o")
+			return
+		}
+		opts := &dockercli.ExportOptions{Format: format}
+		opts.EnvFiles, _ = cmd.Flags().GetBool("env-files")
+		if opts.EnvFiles && output == "" {
+			ezap.Error("--env-files requires an output directory, set it with -o")
+			return
+		}
+		opts.Pretty, _ = cmd.Flags().GetBool("pretty")
+		opts.SingleFile, _ = cmd.Flags().GetBool("single-file")
+		opts.External, _ = cmd.Flags().GetBool("external")
+		if !opts.External {
+			opts.Networks, err = DockerClient.InspectNetworks(cjson)
+			if err != nil {
+				ezap.Warnf("Error inspecting networks, declaring them as external: %v", err)
+			}
+			opts.Volumes, err = DockerClient.InspectVolumes(cjson)
+			if err != nil {
+				ezap.Warnf("Error inspecting volumes, exporting them without driver options and labels: %v", err)
+			}
+		}
+		opts.CgroupnsDefault, err = DockerClient.DefaultCgroupnsMode()
+		if err != nil {
+			ezap.Warnf("Error getting the default cgroup namespace mode, assuming private: %v", err)
+		}
+		opts.Swarm, _ = cmd.Flags().GetBool("swarm")
+		opts.TerraformImport, _ = cmd.Flags().GetBool("terraform-import")
+		opts.Minimal, _ = cmd.Flags().GetBool("minimal")
+		if opts.Minimal {
+			opts.Images, err = DockerClient.InspectImages(cjson)
+			if err != nil {
+				ezap.Error(err)
+				return
+			}
+		}
+		opts.Redactor, err = newRedactor(cmd)
+		if err != nil {
+			ezap.Error(err)
+			return
+		}
+		// json 用于备份和恢复，脱敏时需要保存原始值供 import 还原，未指定 --secrets-file 时写入输出目录
+		secretsFile, _ := cmd.Flags().GetString("secrets-file")
+		if format == "json" && secretsFile == "" && output != "" {
+			secretsFile = path.Join(output, dockercli.SecretsFile)
+		}
+		dump, err := dockercli.ParseContainers(cjson, opts)
+		if err != nil {
+			ezap.Error(err)
+			return
+		}
 
 		switch t := dump.(type) {
 		case string:
@@ -85,13 +137,71 @@ replicating setups or troubleshooting issues.`,
 				}
 			}
 			ezap.Println(out)
+		case dockercli.Files:
+			var out string
+			for _, name := range t.Names() {
+				out += "# " + name + "\n"
+				out += t[name] + "\n"
+				if output != "" {
+					os.MkdirAll(output, 0755)
+					filename := path.Join(output, name)
+					ezap.Infof("Writing to %s\n", filename)
+					err := os.WriteFile(filename, []byte(t[name]), 0644)
+					if err != nil {
+						ezap.Fatal(err)
+					}
+				}
+			}
+			ezap.Println(out)
 		default:
 			ezap.Fatal("Unknown error")
 		}
 
+		// files referenced by the output, e.g. seccomp profiles and env files,
+		// written with 0600 permissions because env files may contain credentials
+		for _, name := range opts.ExtraFiles.Names() {
+			if output == "" {
+				ezap.Warnf("%s is referenced by the output but not written, set -o to write it", name)
+				continue
+			}
+			filename := path.Join(output, name)
+			ezap.Infof("Writing to %s\n", filename)
+			if err := os.WriteFile(filename, []byte(opts.ExtraFiles[name]), 0600); err != nil {
+				ezap.Fatal(err)
+			}
+		}
+
+		if opts.Redactor != nil && len(opts.Redactor.Secrets) > 0 {
+			if secretsFile == "" && format == "json" {
+				ezap.Warnf("Redacted %d values, set -o or --secrets-file to keep them, import cannot restore them otherwise", len(opts.Redactor.Secrets))
+			} else if secretsFile == "" {
+				ezap.Infof("Redacted %d values, set --secrets-file to keep them", len(opts.Redactor.Secrets))
+			} else {
+				ezap.Infof("Writing %d redacted values to %s", len(opts.Redactor.Secrets), secretsFile)
+				if err := opts.Redactor.WriteSecretsFile(secretsFile); err != nil {
+					ezap.Fatal(err)
+				}
+			}
+		}
+
+		if withData {
+			manifest, err := DockerClient.ExportData(cjson, path.Join(output, dockercli.DataDir))
+			if err != nil {
+				ezap.Fatal(err)
+			}
+			ezap.Infof("Archived %d mounts to %s", len(manifest.Archives), path.Join(output, dockercli.DataDir))
+		}
 	},
 }
 
+// newRedactor 根据 --redact 和配置文件中的 redact-patterns 创建 Redactor，关闭脱敏时返回 nil
+func newRedactor(cmd *cobra.Command) (*dockercli.Redactor, error) {
+	if redact, _ := cmd.Flags().GetBool("redact"); !redact {
+		return nil, nil
+	}
+	return dockercli.NewRedactor(viper.GetStringSlice("redact-patterns"))
+}
+
 func init() {
 	rootCmd.AddCommand(exportCmd)
 
@@ -99,5 +209,14 @@ func init() {
 	exportCmd.Flags().BoolP("all", "a", false, "Include stopped containers in the output")
 	exportCmd.Flags().BoolP("pretty", "p", false, "Pretty-print the output")
 	exportCmd.Flags().StringP("output-dir", "o", "", "Set output directory for the generated files, if not set, output to stdout")
-	exportCmd.Flags().StringP("format", "f", "command", "Set output format (eg. command (shell), compose (yaml))")
+	exportCmd.Flags().StringP("format", "f", "command", "Set output format (eg. command (shell), compose (yaml), kubernetes (yaml manifests), quadlet (podman systemd units), systemd (service units), nomad (hcl job), ansible (playbook), terraform (hcl))")
+	exportCmd.Flags().BoolP("single-file", "s", false, "Write all services into a single compose file with top-level networks and volumes, or all kubernetes manifests into one file")
+	exportCmd.Flags().BoolP("minimal", "m", false, "Only export the settings that differ from the image defaults")
+	exportCmd.Flags().Bool("swarm", false, "Write a single compose file for docker stack deploy with deploy sections, dropping options swarm services do not support")
+	exportCmd.Flags().Bool("terraform-import", false, "Add import blocks to terraform output so existing containers, networks and volumes are adopted instead of recreated")
+	exportCmd.Flags().Bool("redact", true, "Replace environment variables and labels that look like secrets (names containing PASSWORD, TOKEN, SECRET, ... or matching redact-patterns in the config file) with ${VAR} placeholders")
+	exportCmd.Flags().String("secrets-file", "", "Write the redacted values to this file with 0600 permissions, it can be sourced by a shell or used as the .env file of docker compose (json output defaults to secrets.env in the output directory)")
+	exportCmd.Flags().Bool("with-data", false, "Archive the contents of bind mounts and volumes into the data directory of the output directory, with a manifest of sizes and checksums")
+	exportCmd.Flags().Bool("env-files", false, "Write each container's environment into <name>.env in the output directory and reference it with --env-file in command output or env_file in compose output")
+	exportCmd.Flags().Bool("external", false, "Reference existing networks and volumes in command, compose, quadlet and ansible output instead of defining them")
 }
@@ -23,32 +23,161 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
+	"github.com/fimreal/docker-exporter/dockercli"
+	"github.com/fimreal/goutils/ezap"
 	"github.com/spf13/cobra"
 )
 
 // importCmd represents the import command
 var importCmd = &cobra.Command{
-	Use:   "import",
+	Use:   "import <FILE|DIR>",
 	Short: "Import a Docker container configuration from a file",
 	Long: `The import command allows users to recreate a Docker container 
 from a previously exported configuration file. This can help in restoring 
-container setups quickly and efficiently.`,
+container setups quickly and efficiently.
+
+Supported inputs are the JSON written by "export -f json", the compose 
+files written by "export -f compose" (a single file or the output directory) 
+and the docker run scripts written by "export -f command".`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("未实现该功能，请等待后续更新")
+		start, _ := cmd.Flags().GetBool("start")
+		format, _ := cmd.Flags().GetString("format")
+		withData, _ := cmd.Flags().GetBool("with-data")
+
+		var secrets map[string]string
+		secretsFile, _ := cmd.Flags().GetString("secrets-file")
+		if secretsFile == "" {
+			// export -f json -o 默认将脱敏的值写入输出目录
+			if _, err := os.Stat(filepath.Join(inputDir(args[0]), dockercli.SecretsFile)); err == nil {
+				secretsFile = filepath.Join(inputDir(args[0]), dockercli.SecretsFile)
+				ezap.Infof("Restoring redacted values from %s", secretsFile)
+			}
+		}
+		if secretsFile != "" {
+			var err error
+			secrets, err = dockercli.LoadSecretsFile(secretsFile)
+			if err != nil {
+				ezap.Error(err)
+				return
+			}
+			// command 和 compose 导入时从环境变量中展开占位符
+			for name, value := range secrets {
+				os.Setenv(name, value)
+			}
+		}
+
+		specs, err := loadSpecs(args[0], format, secrets)
+		if err != nil {
+			ezap.Error(err)
+			return
+		}
+		// 被 link、volumes-from 等引用的容器需要先创建
+		specs, err = dockercli.SortSpecs(specs)
+		if err != nil {
+			ezap.Error(err)
+			return
+		}
+
+		var manifest *dockercli.DataManifest
+		dataDir := filepath.Join(inputDir(args[0]), dockercli.DataDir)
+		if withData {
+			manifest, err = dockercli.LoadDataManifest(dataDir)
+			if err != nil {
+				ezap.Error(err)
+				return
+			}
+			// 已存在的卷在创建容器之前判断，创建容器时会自动创建卷
+			if err := DockerClient.SkipExistingVolumes(manifest); err != nil {
+				ezap.Error(err)
+				return
+			}
+		}
+
+		for _, spec := range specs {
+			// 恢复数据时先创建容器，写入数据之后再启动
+			id, err := DockerClient.CreateContainer(spec, start && manifest == nil)
+			if err != nil {
+				ezap.Error(err)
+				continue
+			}
+			ezap.Infof("Created container %s (%s)", spec.Name, id[:12])
+			if manifest == nil {
+				continue
+			}
+			if err := DockerClient.RestoreData(id, spec.Name, manifest, dataDir); err != nil {
+				ezap.Error(err)
+				continue
+			}
+			if start {
+				if err := DockerClient.StartContainer(id); err != nil {
+					ezap.Errorf("error starting container %s: %v", spec.Name, err)
+				}
+			}
+		}
 	},
 }
 
-func init() {
-	rootCmd.AddCommand(importCmd)
+// loadSpecs 按照指定格式读取导出文件，格式为 auto 时根据文件扩展名判断，
+// secrets 用于还原 JSON 中脱敏的值
+func loadSpecs(filename, format string, secrets map[string]string) ([]dockercli.ContainerSpec, error) {
+	if format == "auto" {
+		format = detectFormat(filename)
+	}
 
-	// Here you will define your flags and configuration settings.
+	switch format {
+	case "json":
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		specs, err := dockercli.LoadContainersJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		dockercli.RestoreSecrets(specs, secrets)
+		return specs, nil
+	case "compose", "yaml", "yml":
+		return dockercli.LoadCompose(filename)
+	case "command", "cmd", "shell", "sh":
+		return dockercli.LoadCommands(filename)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q for %s", format, filename)
+	}
+}
+
+// inputDir 返回导入文件所在的目录，目录本身原样返回
+func inputDir(filename string) string {
+	if info, err := os.Stat(filename); err == nil && info.IsDir() {
+		return filename
+	}
+	return filepath.Dir(filename)
+}
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// importCmd.PersistentFlags().String("foo", "", "A help for foo")
+// detectFormat 根据文件扩展名判断导入格式，目录视为 compose 文件目录
+func detectFormat(filename string) string {
+	if info, err := os.Stat(filename); err == nil && info.IsDir() {
+		return "compose"
+	}
+	switch filepath.Ext(filename) {
+	case ".json":
+		return "json"
+	case ".yml", ".yaml":
+		return "compose"
+	case ".sh":
+		return "command"
+	default:
+		return ""
+	}
+}
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// importCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().BoolP("start", "s", false, "Start the containers after they are created")
+	importCmd.Flags().Bool("with-data", false, "Restore the data archives written by \"export --with-data\" into the mounts before the containers start")
+	importCmd.Flags().String("secrets-file", "", "Restore the values redacted by export from this secrets file, defaults to secrets.env next to the input if it exists")
+	importCmd.Flags().StringP("format", "f", "auto", "Set input format (eg. json, compose (yaml), command (shell)), auto detects it from the file extension")
 }
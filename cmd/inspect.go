@@ -45,10 +45,21 @@ var inspectCmd = &cobra.Command{
 			ezap.Error(err)
 			return
 		}
-		ezap.Println(dockercli.Containers2JSON(containersJSON))
+		redactor, err := newRedactor(cmd)
+		if err != nil {
+			ezap.Error(err)
+			return
+		}
+		dump, err := dockercli.ParseContainers(containersJSON, &dockercli.ExportOptions{Format: "json", Redactor: redactor})
+		if err != nil {
+			ezap.Error(err)
+			return
+		}
+		ezap.Println(dump.(string))
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(inspectCmd)
+	inspectCmd.Flags().Bool("redact", true, "Replace environment variables and labels that look like secrets with ${VAR} placeholders")
 }
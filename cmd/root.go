@@ -85,8 +85,10 @@ func initConfig() {
 	viper.AutomaticEnv() // read in environment variables that match
 
 	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		ezap.Errorf("Error reading config file[%s]: %v", viper.ConfigFileUsed(), err)
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			ezap.Errorf("Error reading config file[%s]: %v", viper.ConfigFileUsed(), err)
+		}
 	}
 
 	viper.BindPFlags(rootCmd.Flags())
@@ -0,0 +1,510 @@
+package dockercli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/blkiodev"
+	"github.com/docker/docker/api/types/network"
+	"github.com/fimreal/goutils/ezap"
+)
+
+// ansiblePlaybookName 导出的 playbook 文件名
+const ansiblePlaybookName = "docker-containers.yml"
+
+// Containers2Ansible 生成 ansible playbook，先创建自定义网络和命名卷，
+// 再按依赖顺序为每个容器生成一个 community.docker.docker_container 任务
+func Containers2Ansible(containersJSON []types.ContainerJSON, opts *ExportOptions) (Files, error) {
+	graph, err := buildContainerGraph(containersJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	var playbook strings.Builder
+	playbook.WriteString("- name: Docker containers\n")
+	playbook.WriteString("  hosts: all\n")
+	playbook.WriteString("  become: true\n")
+	playbook.WriteString("  tasks:\n")
+
+	if !opts.External {
+		seen := make(map[string]bool)
+		for _, containerJSON := range containersJSON {
+			for _, name := range userNetworks(containerJSON) {
+				if seen["network:"+name] {
+					continue
+				}
+				seen["network:"+name] = true
+				playbook.WriteString(fmt.Sprintf("    - name: %s\n", ansibleQuote("Network "+name)))
+				playbook.WriteString("      community.docker.docker_network:\n")
+				playbook.WriteString(fmt.Sprintf("        name: %s\n", ansibleQuote(name)))
+				if networkJSON, ok := opts.Networks[name]; ok {
+					playbook.WriteString(fmt.Sprintf("        driver: %s\n", ansibleQuote(networkJSON.Driver)))
+					writeAnsibleMap(&playbook, "        driver_options", networkJSON.Options)
+				}
+			}
+			for _, mount := range namedVolumes(containerJSON) {
+				if seen["volume:"+mount.Name] {
+					continue
+				}
+				seen["volume:"+mount.Name] = true
+				playbook.WriteString(fmt.Sprintf("    - name: %s\n", ansibleQuote("Volume "+mount.Name)))
+				playbook.WriteString("      community.docker.docker_volume:\n")
+				playbook.WriteString(fmt.Sprintf("        name: %s\n", ansibleQuote(mount.Name)))
+				volumeJSON := volumeDefinition(mount, opts.Volumes)
+				if volumeJSON.Driver != "" {
+					playbook.WriteString(fmt.Sprintf("        driver: %s\n", ansibleQuote(volumeJSON.Driver)))
+				}
+				writeAnsibleMap(&playbook, "        driver_options", volumeJSON.Options)
+				writeAnsibleMap(&playbook, "        labels", userLabels(volumeJSON.Labels))
+			}
+		}
+	}
+
+	for _, containerJSON := range graph.sortContainers(containersJSON) {
+		playbook.WriteString(generateAnsibleTask(containerJSON, graph))
+	}
+	return Files{ansiblePlaybookName: playbook.String()}, nil
+}
+
+// generateAnsibleTask 生成单个容器的 docker_container 任务，参数与 buildDockerRunCommand 一一对应
+func generateAnsibleTask(containerJSON types.ContainerJSON, graph *containerGraph) string {
+	var task strings.Builder
+	cname := strings.TrimPrefix(containerJSON.Name, "/")
+	config := containerJSON.Config
+	hostConfig := containerJSON.HostConfig
+
+	task.WriteString(fmt.Sprintf("    - name: %s\n", ansibleQuote("Container "+cname)))
+	task.WriteString("      community.docker.docker_container:\n")
+	task.WriteString(fmt.Sprintf("        name: %s\n", ansibleQuote(cname)))
+	task.WriteString(fmt.Sprintf("        image: %s\n", ansibleQuote(config.Image)))
+	task.WriteString("        state: started\n")
+
+	// hostname
+	if hostname := customHostname(containerJSON); hostname != "" {
+		task.WriteString(fmt.Sprintf("        hostname: %s\n", ansibleQuote(hostname)))
+	}
+
+	// restart policy
+	if hostConfig.RestartPolicy.Name != "" {
+		task.WriteString(fmt.Sprintf("        restart_policy: %s\n", ansibleQuote(string(hostConfig.RestartPolicy.Name))))
+		if hostConfig.RestartPolicy.MaximumRetryCount > 0 {
+			task.WriteString(fmt.Sprintf("        restart_retries: %d\n", hostConfig.RestartPolicy.MaximumRetryCount))
+		}
+	}
+
+	// user
+	if config.User != "" {
+		task.WriteString(fmt.Sprintf("        user: %s\n", ansibleQuote(config.User)))
+	}
+
+	// workdir
+	if config.WorkingDir != "" {
+		task.WriteString(fmt.Sprintf("        working_dir: %s\n", ansibleQuote(config.WorkingDir)))
+	}
+
+	// entrypoint
+	if len(config.Entrypoint) > 0 {
+		writeAnsibleList(&task, "        entrypoint", config.Entrypoint)
+	}
+
+	// command
+	if len(config.Cmd) > 0 {
+		writeAnsibleList(&task, "        command", config.Cmd)
+	}
+
+	// environment variables
+	env := make(map[string]string)
+	for _, e := range config.Env {
+		key, value, found := strings.Cut(e, "=")
+		if !found || e == "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin" {
+			continue
+		}
+		env[key] = value
+	}
+	writeAnsibleMap(&task, "        env", env)
+
+	// host-to-IP mapping
+	etcHosts := make(map[string]string)
+	for _, alias := range hostAliases(hostConfig.ExtraHosts) {
+		for _, hostname := range alias.hostnames {
+			etcHosts[hostname] = alias.ip
+		}
+	}
+	writeAnsibleMap(&task, "        etc_hosts", etcHosts)
+
+	// privileged mode
+	if hostConfig.Privileged {
+		task.WriteString("        privileged: true\n")
+	}
+
+	// capabilities
+	writeAnsibleList(&task, "        capabilities", hostConfig.CapAdd)
+	writeAnsibleList(&task, "        cap_drop", hostConfig.CapDrop)
+
+	// readonly root fs
+	if hostConfig.ReadonlyRootfs {
+		task.WriteString("        read_only: true\n")
+	}
+
+	// security options
+	writeAnsibleList(&task, "        security_opts", securityOptions(hostConfig))
+
+	// supplementary groups
+	writeAnsibleList(&task, "        groups", hostConfig.GroupAdd)
+
+	// OOM score adjustment
+	if hostConfig.OomScoreAdj != 0 {
+		task.WriteString(fmt.Sprintf("        oom_score_adj: %d\n", hostConfig.OomScoreAdj))
+	}
+
+	// OOM killer，oom_killer 为 true 时禁用 OOM killer
+	if oomKillDisable := hostConfig.OomKillDisable; oomKillDisable != nil && *oomKillDisable {
+		task.WriteString("        oom_killer: true\n")
+	}
+
+	// user namespace mode
+	if hostConfig.UsernsMode != "" {
+		task.WriteString(fmt.Sprintf("        userns_mode: %s\n", ansibleQuote(string(hostConfig.UsernsMode))))
+	}
+
+	// uts mode
+	if hostConfig.UTSMode.IsHost() {
+		task.WriteString("        uts: \"host\"\n")
+	}
+
+	// cgroup namespace mode
+	if hostConfig.CgroupnsMode != "" {
+		task.WriteString(fmt.Sprintf("        cgroupns_mode: %s\n", ansibleQuote(string(hostConfig.CgroupnsMode))))
+	}
+
+	// pid mode
+	if ref, ok := graph.ref(cname, refPid); ok {
+		task.WriteString(fmt.Sprintf("        pid_mode: %s\n", ansibleQuote("container:"+ref.Target)))
+	} else if hostConfig.PidMode != "" {
+		task.WriteString(fmt.Sprintf("        pid_mode: %s\n", ansibleQuote(string(hostConfig.PidMode))))
+	}
+
+	// ipc mode
+	if ref, ok := graph.ref(cname, refIpc); ok {
+		task.WriteString(fmt.Sprintf("        ipc_mode: %s\n", ansibleQuote("container:"+ref.Target)))
+	} else if ipcMode := hostConfig.IpcMode; ipcMode.IsHost() || ipcMode.IsNone() {
+		task.WriteString(fmt.Sprintf("        ipc_mode: %s\n", ansibleQuote(string(ipcMode))))
+	}
+
+	// link
+	var links []string
+	for _, ref := range graph.refsOf(cname, refLink) {
+		if ref.Option != "" {
+			links = append(links, ref.Target+":"+ref.Option)
+		} else {
+			links = append(links, ref.Target)
+		}
+	}
+	writeAnsibleList(&task, "        links", links)
+
+	// volumes from
+	var volumesFrom []string
+	for _, ref := range graph.refsOf(cname, refVolumesFrom) {
+		if ref.Option != "" {
+			volumesFrom = append(volumesFrom, ref.Target+":"+ref.Option)
+		} else {
+			volumesFrom = append(volumesFrom, ref.Target)
+		}
+	}
+	writeAnsibleList(&task, "        volumes_from", volumesFrom)
+
+	// cpu limit
+	if hostConfig.NanoCPUs > 0 {
+		task.WriteString(fmt.Sprintf("        cpus: %s\n", strconv.FormatFloat(float64(hostConfig.NanoCPUs)/1e9, 'f', -1, 64)))
+	}
+
+	// cpu shares
+	if hostConfig.CPUShares > 0 {
+		task.WriteString(fmt.Sprintf("        cpu_shares: %d\n", hostConfig.CPUShares))
+	}
+
+	// cpuset
+	if hostConfig.CpusetCpus != "" {
+		task.WriteString(fmt.Sprintf("        cpuset_cpus: %s\n", ansibleQuote(hostConfig.CpusetCpus)))
+	}
+
+	if hostConfig.CpusetMems != "" {
+		task.WriteString(fmt.Sprintf("        cpuset_mems: %s\n", ansibleQuote(hostConfig.CpusetMems)))
+	}
+
+	// cpu quota
+	if hostConfig.CPUPeriod > 0 {
+		task.WriteString(fmt.Sprintf("        cpu_period: %d\n", hostConfig.CPUPeriod))
+	}
+	if hostConfig.CPUQuota != 0 {
+		task.WriteString(fmt.Sprintf("        cpu_quota: %d\n", hostConfig.CPUQuota))
+	}
+	if hostConfig.CPURealtimePeriod > 0 || hostConfig.CPURealtimeRuntime > 0 {
+		ezap.Warnf("%s: cpu realtime scheduling is not supported by the docker_container module, dropped", cname)
+	}
+
+	// memory limit
+	if hostConfig.Memory > 0 {
+		task.WriteString(fmt.Sprintf("        memory: %s\n", bytesSize(hostConfig.Memory)))
+	}
+	if hostConfig.MemoryReservation > 0 {
+		task.WriteString(fmt.Sprintf("        memory_reservation: %s\n", bytesSize(hostConfig.MemoryReservation)))
+	}
+	if swap := memorySwap(hostConfig.MemorySwap); swap != "" {
+		task.WriteString(fmt.Sprintf("        memory_swap: %s\n", ansibleQuote(swap)))
+	}
+	if hostConfig.MemorySwappiness != nil && *hostConfig.MemorySwappiness >= 0 {
+		task.WriteString(fmt.Sprintf("        memory_swappiness: %d\n", *hostConfig.MemorySwappiness))
+	}
+	if hostConfig.KernelMemory > 0 {
+		task.WriteString(fmt.Sprintf("        kernel_memory: %s\n", bytesSize(hostConfig.KernelMemory)))
+	}
+	if hostConfig.PidsLimit != nil && *hostConfig.PidsLimit > 0 {
+		task.WriteString(fmt.Sprintf("        pids_limit: %d\n", *hostConfig.PidsLimit))
+	}
+
+	// block io
+	if hostConfig.BlkioWeight > 0 {
+		task.WriteString(fmt.Sprintf("        blkio_weight: %d\n", hostConfig.BlkioWeight))
+	}
+	if len(hostConfig.BlkioWeightDevice) > 0 {
+		ezap.Warnf("%s: --blkio-weight-device is not supported by the docker_container module, dropped", cname)
+	}
+	for _, throttle := range []struct {
+		key     string
+		devices []*blkiodev.ThrottleDevice
+		bytes   bool
+	}{
+		{"device_read_bps", hostConfig.BlkioDeviceReadBps, true},
+		{"device_write_bps", hostConfig.BlkioDeviceWriteBps, true},
+		{"device_read_iops", hostConfig.BlkioDeviceReadIOps, false},
+		{"device_write_iops", hostConfig.BlkioDeviceWriteIOps, false},
+	} {
+		if len(throttle.devices) == 0 {
+			continue
+		}
+		task.WriteString(fmt.Sprintf("        %s:\n", throttle.key))
+		for _, device := range throttle.devices {
+			rate := strconv.FormatUint(device.Rate, 10)
+			if throttle.bytes {
+				rate = bytesSize(int64(device.Rate))
+			}
+			task.WriteString(fmt.Sprintf("          - path: %s\n", ansibleQuote(device.Path)))
+			task.WriteString(fmt.Sprintf("            rate: %s\n", ansibleQuote(rate)))
+		}
+	}
+
+	// shared memory size
+	if shmSize := hostConfig.ShmSize; shmSize > 0 && shmSize != defaultShmSize {
+		task.WriteString(fmt.Sprintf("        shm_size: %s\n", bytesSize(shmSize)))
+	}
+
+	// ulimits
+	var ulimits []string
+	for _, ulimit := range hostConfig.Ulimits {
+		ulimits = append(ulimits, fmt.Sprintf("%s:%d:%d", ulimit.Name, ulimit.Soft, ulimit.Hard))
+	}
+	writeAnsibleList(&task, "        ulimits", ulimits)
+
+	// sysctls
+	writeAnsibleMap(&task, "        sysctls", hostConfig.Sysctls)
+
+	// tmpfs
+	var tmpfs []string
+	for _, path := range sortedKeys(hostConfig.Tmpfs) {
+		tmpfs = append(tmpfs, tmpfsSpec(path, hostConfig.Tmpfs[path]))
+	}
+	writeAnsibleList(&task, "        tmpfs", tmpfs)
+
+	// storage options
+	writeAnsibleMap(&task, "        storage_opts", hostConfig.StorageOpt)
+
+	// network mode
+	if ref, ok := graph.ref(cname, refNetwork); ok {
+		task.WriteString(fmt.Sprintf("        network_mode: %s\n", ansibleQuote("container:"+ref.Target)))
+	} else if hostConfig.NetworkMode != "" && hostConfig.NetworkMode != "default" {
+		task.WriteString(fmt.Sprintf("        network_mode: %s\n", ansibleQuote(string(hostConfig.NetworkMode))))
+	}
+	// 默认 bridge 网络中的 MAC 地址
+	if primary := primaryNetwork(containerJSON); isBuiltinNetwork(primary) {
+		if endpoint := networkEndpoint(containerJSON, primary); endpoint != nil && customMacAddress(endpoint) != "" {
+			task.WriteString(fmt.Sprintf("        mac_address: %s\n", ansibleQuote(customMacAddress(endpoint))))
+		}
+	}
+	if networks := userNetworks(containerJSON); len(networks) > 0 {
+		task.WriteString("        networks:\n")
+		for _, name := range networks {
+			task.WriteString(fmt.Sprintf("          - name: %s\n", ansibleQuote(name)))
+			writeAnsibleEndpoint(&task, containerJSON, networkEndpoint(containerJSON, name))
+		}
+	}
+
+	// dns
+	writeAnsibleList(&task, "        dns_servers", hostConfig.DNS)
+
+	// port mapping
+	var ports []string
+	addedPorts := make(map[string]bool)
+	for _, port := range containerPorts(containerJSON) {
+		for _, binding := range containerJSON.NetworkSettings.Ports[port] {
+			portMapping := portSpec(port, binding)
+			if !addedPorts[portMapping] {
+				addedPorts[portMapping] = true
+				ports = append(ports, portMapping)
+			}
+		}
+	}
+	writeAnsibleList(&task, "        published_ports", ports)
+
+	// exposed ports without a published binding
+	var exposed []string
+	for _, port := range exposedOnlyPorts(containerJSON) {
+		exposed = append(exposed, string(port))
+	}
+	writeAnsibleList(&task, "        exposed_ports", exposed)
+
+	// mount
+	var volumes []string
+	for _, mount := range containerJSON.Mounts {
+		var volume string
+		switch {
+		case mount.Type == "bind":
+			volume = mount.Source + ":" + mount.Destination
+		case mount.Type == "volume" && mount.Name != "" && !isAnonymousVolume(mount.Name):
+			volume = mount.Name + ":" + mount.Destination
+		case mount.Type == "volume":
+			volume = mount.Destination
+		default:
+			continue
+		}
+		if options := shortMountOptions(mount); len(options) > 0 {
+			volume += ":" + strings.Join(options, ",")
+		}
+		volumes = append(volumes, volume)
+	}
+	writeAnsibleList(&task, "        volumes", volumes)
+
+	// devices
+	var devices []string
+	for _, device := range hostConfig.Devices {
+		devices = append(devices, device.PathOnHost+":"+device.PathInContainer)
+	}
+	writeAnsibleList(&task, "        devices", devices)
+
+	// label
+	writeAnsibleMap(&task, "        labels", config.Labels)
+
+	// log driver
+	if hostConfig.LogConfig.Type != "" && hostConfig.LogConfig.Type != "json-file" {
+		task.WriteString(fmt.Sprintf("        log_driver: %s\n", ansibleQuote(hostConfig.LogConfig.Type)))
+	}
+	writeAnsibleMap(&task, "        log_options", hostConfig.LogConfig.Config)
+
+	// healthcheck
+	if healthcheck := config.Healthcheck; healthcheck != nil {
+		task.WriteString("        healthcheck:\n")
+		if len(healthcheck.Test) > 0 {
+			writeAnsibleList(&task, "          test", healthcheck.Test)
+		}
+		if healthcheck.Interval > 0 {
+			task.WriteString(fmt.Sprintf("          interval: %s\n", ansibleDuration(healthcheck.Interval)))
+		}
+		if healthcheck.Timeout > 0 {
+			task.WriteString(fmt.Sprintf("          timeout: %s\n", ansibleDuration(healthcheck.Timeout)))
+		}
+		if healthcheck.StartPeriod > 0 {
+			task.WriteString(fmt.Sprintf("          start_period: %s\n", ansibleDuration(healthcheck.StartPeriod)))
+		}
+		if healthcheck.Retries > 0 {
+			task.WriteString(fmt.Sprintf("          retries: %d\n", healthcheck.Retries))
+		}
+	}
+
+	// init
+	if hostConfig.Init != nil && *hostConfig.Init {
+		task.WriteString("        init: true\n")
+	}
+
+	// stop signal and timeout
+	if config.StopSignal != "" {
+		task.WriteString(fmt.Sprintf("        stop_signal: %s\n", ansibleQuote(config.StopSignal)))
+	}
+	if config.StopTimeout != nil {
+		task.WriteString(fmt.Sprintf("        stop_timeout: %d\n", *config.StopTimeout))
+	}
+
+	return task.String()
+}
+
+// writeAnsibleEndpoint 写入网络中的别名、静态 IP、MAC 地址和 link，对应 docker_container 的 networks 选项
+func writeAnsibleEndpoint(builder *strings.Builder, containerJSON types.ContainerJSON, endpoint *network.EndpointSettings) {
+	if endpoint == nil {
+		return
+	}
+	writeAnsibleList(builder, "            aliases", endpointAliases(containerJSON, endpoint))
+	if ipam := endpoint.IPAMConfig; ipam != nil {
+		if ipam.IPv4Address != "" {
+			builder.WriteString(fmt.Sprintf("            ipv4_address: %s\n", ansibleQuote(ipam.IPv4Address)))
+		}
+		if ipam.IPv6Address != "" {
+			builder.WriteString(fmt.Sprintf("            ipv6_address: %s\n", ansibleQuote(ipam.IPv6Address)))
+		}
+	}
+	if mac := customMacAddress(endpoint); mac != "" {
+		builder.WriteString(fmt.Sprintf("            mac_address: %s\n", ansibleQuote(mac)))
+	}
+	writeAnsibleList(builder, "            links", endpoint.Links)
+}
+
+// ansibleDuration 返回 docker_container 接受的时长，如 1m30s，不支持小数，不足 1 秒的部分使用 ms 和 us
+func ansibleDuration(d time.Duration) string {
+	var duration strings.Builder
+	for _, unit := range []struct {
+		suffix string
+		size   time.Duration
+	}{{"h", time.Hour}, {"m", time.Minute}, {"s", time.Second}, {"ms", time.Millisecond}, {"us", time.Microsecond}} {
+		if n := d / unit.size; n > 0 {
+			duration.WriteString(strconv.FormatInt(int64(n), 10) + unit.suffix)
+			d -= n * unit.size
+		}
+	}
+	if duration.Len() == 0 {
+		return "0s"
+	}
+	return duration.String()
+}
+
+// ansibleQuote 返回双引号形式的 yaml 字符串，包含 jinja2 模板语法时标记为 !unsafe，避免被 ansible 展开
+func ansibleQuote(s string) string {
+	if strings.Contains(s, "{{") || strings.Contains(s, "{%") || strings.Contains(s, "{#") {
+		return "!unsafe " + yamlQuote(s)
+	}
+	return yamlQuote(s)
+}
+
+// writeAnsibleList 写入 yaml 列表，空列表不输出
+func writeAnsibleList(builder *strings.Builder, key string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	indent := strings.Repeat(" ", len(key)-len(strings.TrimLeft(key, " ")))
+	builder.WriteString(key + ":\n")
+	for _, item := range items {
+		builder.WriteString(fmt.Sprintf("%s  - %s\n", indent, ansibleQuote(item)))
+	}
+}
+
+// writeAnsibleMap 按照键排序写入 yaml 映射，空映射不输出
+func writeAnsibleMap(builder *strings.Builder, key string, m map[string]string) {
+	if len(m) == 0 {
+		return
+	}
+	indent := strings.Repeat(" ", len(key)-len(strings.TrimLeft(key, " ")))
+	builder.WriteString(key + ":\n")
+	for _, k := range sortedKeys(m) {
+		builder.WriteString(fmt.Sprintf("%s  %s: %s\n", indent, ansibleQuote(k), ansibleQuote(m[k])))
+	}
+}
@@ -0,0 +1,99 @@
+package dockercli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-units"
+)
+
+func TestAnsibleDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		want     string
+	}{
+		{30 * time.Second, "30s"},
+		{90 * time.Second, "1m30s"},
+		{time.Hour, "1h"},
+		{1500 * time.Millisecond, "1s500ms"},
+		{250 * time.Microsecond, "250us"},
+		{0, "0s"},
+	}
+	for _, tt := range tests {
+		if got := ansibleDuration(tt.duration); got != tt.want {
+			t.Errorf("ansibleDuration(%s) = %q, want %q", tt.duration, got, tt.want)
+		}
+	}
+}
+
+func TestAnsibleTask(t *testing.T) {
+	web := testContainer("aaaaaaaaaaaa1111", "web")
+	web.HostConfig.NetworkMode = "app"
+	web.NetworkSettings.Networks = map[string]*network.EndpointSettings{
+		"app": {
+			Aliases:    []string{"web", "aaaaaaaaaaaa", "frontend"},
+			IPAMConfig: &network.EndpointIPAMConfig{IPv4Address: "172.20.0.10", IPv6Address: "fd00::10"},
+			IPAddress:  "172.20.0.10",
+			MacAddress: "92:d0:c6:0a:29:33",
+		},
+		// docker 根据 IP 生成的 MAC 地址不导出
+		"backend": {IPAddress: "172.21.0.2", MacAddress: "02:42:ac:15:00:02"},
+	}
+	init, oomKillDisable, stopTimeout := true, true, 30
+	web.HostConfig.Init = &init
+	web.HostConfig.OomKillDisable = &oomKillDisable
+	web.HostConfig.SecurityOpt = []string{"no-new-privileges"}
+	web.HostConfig.GroupAdd = []string{"audio"}
+	web.HostConfig.Ulimits = []*units.Ulimit{{Name: "nofile", Soft: 1024, Hard: 2048}}
+	web.HostConfig.Sysctls = map[string]string{"net.core.somaxconn": "1024"}
+	web.HostConfig.Tmpfs = map[string]string{"/run": "size=64m"}
+	web.HostConfig.ShmSize = 128 << 20
+	web.HostConfig.StorageOpt = map[string]string{"size": "10G"}
+	web.Config.StopSignal = "SIGQUIT"
+	web.Config.StopTimeout = &stopTimeout
+	web.Config.Healthcheck = &container.HealthConfig{
+		Test:     []string{"CMD-SHELL", "curl -f http://localhost/"},
+		Interval: 90 * time.Second,
+		Timeout:  1500 * time.Millisecond,
+		Retries:  3,
+	}
+
+	playbook := exportString(t, []types.ContainerJSON{web}, &ExportOptions{Format: "ansible"})
+	for _, want := range []string{
+		"          - name: \"app\"\n            aliases:\n              - \"frontend\"\n" +
+			"            ipv4_address: \"172.20.0.10\"\n            ipv6_address: \"fd00::10\"\n            mac_address: \"92:d0:c6:0a:29:33\"\n",
+		"          - name: \"backend\"\n        healthcheck:",
+		"        security_opts:\n          - \"no-new-privileges\"\n",
+		"        groups:\n          - \"audio\"\n",
+		"        oom_killer: true\n",
+		"        shm_size: 128m\n",
+		"        ulimits:\n          - \"nofile:1024:2048\"\n",
+		"        sysctls:\n          \"net.core.somaxconn\": \"1024\"\n",
+		"        tmpfs:\n          - \"/run:size=64m\"\n",
+		"        storage_opts:\n          \"size\": \"10G\"\n",
+		"        healthcheck:\n          test:\n            - \"CMD-SHELL\"\n            - \"curl -f http://localhost/\"\n" +
+			"          interval: 1m30s\n          timeout: 1s500ms\n          retries: 3\n",
+		"        init: true\n",
+		"        stop_signal: \"SIGQUIT\"\n        stop_timeout: 30\n",
+	} {
+		if !strings.Contains(playbook, want) {
+			t.Errorf("missing %q in:\n%s", want, playbook)
+		}
+	}
+	if strings.Contains(playbook, "02:42:ac:15:00:02") {
+		t.Errorf("generated mac address is exported:\n%s", playbook)
+	}
+}
+
+func TestAnsibleBridgeMacAddress(t *testing.T) {
+	web := testContainer("aaaaaaaaaaaa1111", "web")
+	web.NetworkSettings.Networks["bridge"] = &network.EndpointSettings{IPAddress: "172.17.0.2", MacAddress: "92:d0:c6:0a:29:33"}
+	playbook := exportString(t, []types.ContainerJSON{web}, &ExportOptions{Format: "ansible"})
+	if want := "        mac_address: \"92:d0:c6:0a:29:33\"\n"; !strings.Contains(playbook, want) {
+		t.Errorf("missing %q in:\n%s", want, playbook)
+	}
+}
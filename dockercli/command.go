@@ -0,0 +1,891 @@
+package dockercli
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/docker/docker/api/types/blkiodev"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/opts"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
+	"github.com/fimreal/goutils/ezap"
+)
+
+// runFlag docker run 参数定义，hasValue 为 false 的是布尔参数
+type runFlag struct {
+	hasValue bool
+	apply    func(p *runParser, value string) error
+}
+
+// runParser 解析单条 docker run 命令时的状态
+type runParser struct {
+	spec     ContainerSpec
+	endpoint *network.EndpointSettings
+	baseDir  string // 脚本所在目录，用于解析参数中的相对路径
+}
+
+// LoadCommands 解析 Containers2CMD 生成的 docker run 脚本
+func LoadCommands(filename string) ([]ContainerSpec, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	commands, err := splitShellCommands(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	// --env-file 等参数通过 "$(dirname "$0")" 引用脚本所在目录中的文件
+	scriptDir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return nil, err
+	}
+	for _, words := range commands {
+		for i := range words {
+			words[i].Value = strings.ReplaceAll(words[i].Value, scriptDirMarker, scriptDir)
+		}
+	}
+
+	var specs []ContainerSpec
+	var errs []error
+	for _, words := range commands {
+		// 连接多个网络的容器导出为 docker create、docker network connect 和 docker start
+		if args, ok := dockerCommandArgs(words, "network", "connect"); ok {
+			if err := connectNetwork(specs, args, words[0].Line); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if _, ok := dockerCommandArgs(words, "start"); ok {
+			continue
+		}
+		// 导入时不创建网络和卷，使用已存在的网络，命名卷由 docker 在创建容器时自动创建
+		if _, ok := dockerCommandArgs(words, "network", "create"); ok {
+			continue
+		}
+		if _, ok := dockerCommandArgs(words, "volume", "create"); ok {
+			continue
+		}
+
+		args, ok := dockerRunArgs(words)
+		if !ok {
+			ezap.Warnf("%s: line %d: skipping unsupported command %q", filename, words[0].Line, words[0].Value)
+			continue
+		}
+		spec, err := parseDockerRun(args, filepath.Dir(filename))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		specs = append(specs, spec)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s: %w", filename, errors.Join(errs...))
+	}
+	return specs, nil
+}
+
+// dockerRunArgs 判断是否为 docker run/create 命令，返回命令之后的参数
+func dockerRunArgs(words []shellWord) ([]shellWord, bool) {
+	if len(words) < 2 || filepath.Base(words[0].Value) != "docker" {
+		return nil, false
+	}
+	args := words[1:]
+	if args[0].Value == "container" {
+		args = args[1:]
+	}
+	if len(args) == 0 || (args[0].Value != "run" && args[0].Value != "create") {
+		return nil, false
+	}
+	return args[1:], true
+}
+
+// dockerCommandArgs 判断是否为指定的 docker 子命令，返回子命令之后的参数
+func dockerCommandArgs(words []shellWord, subcommand ...string) ([]shellWord, bool) {
+	if len(words) <= len(subcommand) || filepath.Base(words[0].Value) != "docker" {
+		return nil, false
+	}
+	for i, name := range subcommand {
+		if words[i+1].Value != name {
+			return nil, false
+		}
+	}
+	return words[len(subcommand)+1:], true
+}
+
+// connectNetwork 解析 docker network connect 的参数，将网络的端点配置添加到 specs 中对应的容器，line 为命令所在行
+func connectNetwork(specs []ContainerSpec, args []shellWord, line int) error {
+	endpoint := &network.EndpointSettings{}
+	var positional []shellWord
+	for i := 0; i < len(args); i++ {
+		word := args[i]
+		name, value, hasValue := strings.Cut(word.Value, "=")
+		if !strings.HasPrefix(name, "--") {
+			positional = append(positional, word)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return fmt.Errorf("line %d: flag %s needs an argument", word.Line, name)
+			}
+			i++
+			value = args[i].Value
+		}
+		switch name {
+		case "--alias":
+			endpoint.Aliases = append(endpoint.Aliases, value)
+		case "--ip", "--ip6", "--link-local-ip":
+			if endpoint.IPAMConfig == nil {
+				endpoint.IPAMConfig = &network.EndpointIPAMConfig{}
+			}
+			switch name {
+			case "--ip":
+				endpoint.IPAMConfig.IPv4Address = value
+			case "--ip6":
+				endpoint.IPAMConfig.IPv6Address = value
+			default:
+				endpoint.IPAMConfig.LinkLocalIPs = append(endpoint.IPAMConfig.LinkLocalIPs, value)
+			}
+		case "--link":
+			endpoint.Links = append(endpoint.Links, value)
+		case "--driver-opt":
+			key, opt, _ := strings.Cut(value, "=")
+			if endpoint.DriverOpts == nil {
+				endpoint.DriverOpts = make(map[string]string)
+			}
+			endpoint.DriverOpts[key] = opt
+		default:
+			return fmt.Errorf("line %d: unknown flag %s", word.Line, name)
+		}
+	}
+	if len(positional) != 2 {
+		return fmt.Errorf("line %d: docker network connect needs a network and a container", line)
+	}
+
+	networkName, containerName := positional[0].Value, positional[1].Value
+	for i := range specs {
+		if specs[i].Name != containerName {
+			continue
+		}
+		if specs[i].NetworkingConfig.EndpointsConfig == nil {
+			specs[i].NetworkingConfig.EndpointsConfig = make(map[string]*network.EndpointSettings)
+		}
+		specs[i].NetworkingConfig.EndpointsConfig[networkName] = endpoint
+		return nil
+	}
+	return fmt.Errorf("line %d: container %s is not created before connecting it to network %s", positional[1].Line, containerName, networkName)
+}
+
+// parseDockerRun 将 docker run 的参数转换为创建容器的参数，所有错误都带有行号，
+// 参数中的相对路径以 baseDir 为基准
+func parseDockerRun(args []shellWord, baseDir string) (ContainerSpec, error) {
+	p := &runParser{
+		spec: ContainerSpec{
+			Config:           &container.Config{},
+			HostConfig:       &container.HostConfig{},
+			NetworkingConfig: &network.NetworkingConfig{},
+		},
+		baseDir: baseDir,
+	}
+
+	var errs []error
+	fail := func(word shellWord, format string, a ...any) {
+		errs = append(errs, fmt.Errorf("line %d: "+format, append([]any{word.Line}, a...)...))
+	}
+	apply := func(word shellWord, name string, flag *runFlag, value string) {
+		if err := flag.apply(p, value); err != nil {
+			fail(word, "%s: %v", name, err)
+		}
+	}
+
+	i := 0
+	for ; i < len(args); i++ {
+		word := args[i]
+		arg := word.Value
+		if arg == "--" {
+			i++
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			break
+		}
+
+		if strings.HasPrefix(arg, "--") {
+			name, value, hasValue := strings.Cut(arg, "=")
+			flag, ok := runFlags[name]
+			if !ok {
+				fail(word, "unknown flag %s", name)
+				continue
+			}
+			if flag.hasValue && !hasValue {
+				if i+1 >= len(args) {
+					fail(word, "flag %s needs an argument", name)
+					continue
+				}
+				i++
+				value = args[i].Value
+			} else if !flag.hasValue && !hasValue {
+				value = "true"
+			}
+			apply(word, name, flag, value)
+			continue
+		}
+
+		// 短参数，可能是 -it 这样的组合，或 -p8080:80 这样紧跟参数值
+		shorts := arg[1:]
+		for j := 0; j < len(shorts); j++ {
+			name := "-" + string(shorts[j])
+			flag, ok := runFlags[name]
+			if !ok {
+				fail(word, "unknown flag %s", name)
+				break
+			}
+			if !flag.hasValue {
+				apply(word, name, flag, "true")
+				continue
+			}
+			value := strings.TrimPrefix(shorts[j+1:], "=")
+			if value == "" {
+				if i+1 >= len(args) {
+					fail(word, "flag %s needs an argument", name)
+					break
+				}
+				i++
+				value = args[i].Value
+			}
+			apply(word, name, flag, value)
+			break
+		}
+	}
+
+	if i >= len(args) {
+		line := 0
+		if len(args) > 0 {
+			line = args[len(args)-1].Line
+		}
+		errs = append(errs, fmt.Errorf("line %d: image is required", line))
+		return ContainerSpec{}, errors.Join(errs...)
+	}
+	p.spec.Config.Image = args[i].Value
+	for _, word := range args[i+1:] {
+		p.spec.Config.Cmd = append(p.spec.Config.Cmd, word.Value)
+	}
+
+	if p.endpoint != nil {
+		networkName := string(p.spec.HostConfig.NetworkMode)
+		if networkName == "" || networkName == "default" {
+			networkName = "bridge"
+		}
+		p.spec.NetworkingConfig.EndpointsConfig = map[string]*network.EndpointSettings{networkName: p.endpoint}
+	}
+
+	if len(errs) > 0 {
+		return ContainerSpec{}, errors.Join(errs...)
+	}
+	return p.spec, nil
+}
+
+// endpointSettings 返回 --network 对应网络的端点配置
+func (p *runParser) endpointSettings() *network.EndpointSettings {
+	if p.endpoint == nil {
+		p.endpoint = &network.EndpointSettings{}
+	}
+	return p.endpoint
+}
+
+// healthcheck 返回容器的健康检查配置，不存在时创建
+func (p *runParser) healthcheck() *container.HealthConfig {
+	if p.spec.Config.Healthcheck == nil {
+		p.spec.Config.Healthcheck = &container.HealthConfig{}
+	}
+	return p.spec.Config.Healthcheck
+}
+
+// durationFlag 设置时间间隔参数
+func durationFlag(set func(p *runParser, value time.Duration)) *runFlag {
+	return &runFlag{hasValue: true, apply: func(p *runParser, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		set(p, d)
+		return nil
+	}}
+}
+
+// int64Flag 设置整数参数
+func int64Flag(set func(p *runParser, value int64)) *runFlag {
+	return &runFlag{hasValue: true, apply: func(p *runParser, value string) error {
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		set(p, v)
+		return nil
+	}}
+}
+
+// throttleFlag 追加 blkio 限速参数，devices 返回要追加的列表
+func throttleFlag(bytes bool, devices func(r *container.Resources) *[]*blkiodev.ThrottleDevice) *runFlag {
+	return &runFlag{hasValue: true, apply: func(p *runParser, value string) error {
+		device, err := parseThrottleDevice(value, bytes)
+		if err != nil {
+			return err
+		}
+		list := devices(&p.spec.HostConfig.Resources)
+		*list = append(*list, device)
+		return nil
+	}}
+}
+
+// stringFlag 设置字符串参数
+func stringFlag(set func(p *runParser, value string)) *runFlag {
+	return &runFlag{hasValue: true, apply: func(p *runParser, value string) error {
+		set(p, value)
+		return nil
+	}}
+}
+
+// boolFlag 设置布尔参数
+func boolFlag(set func(p *runParser, value bool)) *runFlag {
+	return &runFlag{apply: func(p *runParser, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		set(p, b)
+		return nil
+	}}
+}
+
+// runFlags 支持的 docker run 参数，同一参数的长短形式指向同一定义
+var runFlags = map[string]*runFlag{}
+
+func init() {
+	register := func(flag *runFlag, names ...string) {
+		for _, name := range names {
+			// 重复注册时后面的定义会覆盖前面的，前面的处理永远不会执行
+			if _, exists := runFlags[name]; exists {
+				panic("duplicate docker run flag " + name)
+			}
+			runFlags[name] = flag
+		}
+	}
+
+	register(boolFlag(func(p *runParser, v bool) {}), "-d", "--detach")
+	register(boolFlag(func(p *runParser, v bool) { p.spec.HostConfig.AutoRemove = v }), "--rm")
+	register(boolFlag(func(p *runParser, v bool) { p.spec.Config.OpenStdin = v }), "-i", "--interactive")
+	register(boolFlag(func(p *runParser, v bool) { p.spec.Config.Tty = v }), "-t", "--tty")
+	register(stringFlag(func(p *runParser, v string) { p.spec.Name = v }), "--name")
+	register(stringFlag(func(p *runParser, v string) { p.spec.Config.Hostname = v }), "-h", "--hostname")
+	register(stringFlag(func(p *runParser, v string) { p.spec.Config.Domainname = v }), "--domainname")
+	register(stringFlag(func(p *runParser, v string) { p.spec.Config.User = v }), "-u", "--user")
+	register(stringFlag(func(p *runParser, v string) { p.spec.Config.WorkingDir = v }), "-w", "--workdir")
+	register(stringFlag(func(p *runParser, v string) {
+		// 与 docker cli 一致，--entrypoint "" 表示清空镜像的 entrypoint
+		p.spec.Config.Entrypoint = []string{v}
+	}), "--entrypoint")
+
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		policy, err := parseRestartPolicy(v)
+		if err != nil {
+			return err
+		}
+		p.spec.HostConfig.RestartPolicy = policy
+		return nil
+	}}, "--restart")
+	// 旧版本导出的脚本使用的参数，并非 docker 的参数
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		count, err := strconv.Atoi(v)
+		p.spec.HostConfig.RestartPolicy.MaximumRetryCount = count
+		return err
+	}}, "--restart-max-attempts")
+
+	register(stringFlag(func(p *runParser, v string) {
+		p.spec.Config.Env = append(p.spec.Config.Env, resolveEnv([]string{v})...)
+	}), "-e", "--env")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		if !filepath.IsAbs(v) {
+			v = expandPath(v, p.baseDir)
+		}
+		env, err := readEnvFile(v)
+		p.spec.Config.Env = append(p.spec.Config.Env, env...)
+		return err
+	}}, "--env-file")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		label, err := opts.ValidateLabel(v)
+		if err != nil {
+			return err
+		}
+		if p.spec.Config.Labels == nil {
+			p.spec.Config.Labels = make(map[string]string)
+		}
+		key, value, _ := strings.Cut(label, "=")
+		p.spec.Config.Labels[key] = value
+		return nil
+	}}, "-l", "--label")
+
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		host, err := opts.ValidateExtraHost(v)
+		p.spec.HostConfig.ExtraHosts = append(p.spec.HostConfig.ExtraHosts, host)
+		return err
+	}}, "--add-host")
+	register(boolFlag(func(p *runParser, v bool) { p.spec.HostConfig.Privileged = v }), "--privileged")
+	register(stringFlag(func(p *runParser, v string) {
+		p.spec.HostConfig.CapAdd = append(p.spec.HostConfig.CapAdd, v)
+	}), "--cap-add")
+	register(stringFlag(func(p *runParser, v string) {
+		p.spec.HostConfig.CapDrop = append(p.spec.HostConfig.CapDrop, v)
+	}), "--cap-drop")
+	register(boolFlag(func(p *runParser, v bool) { p.spec.HostConfig.ReadonlyRootfs = v }), "--read-only")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		adj, err := strconv.Atoi(v)
+		p.spec.HostConfig.OomScoreAdj = adj
+		return err
+	}}, "--oom-score-adj")
+	register(stringFlag(func(p *runParser, v string) {
+		p.spec.HostConfig.UsernsMode = container.UsernsMode(v)
+	}), "--userns", "--userns-mode")
+	register(stringFlag(func(p *runParser, v string) { p.spec.HostConfig.PidMode = container.PidMode(v) }), "--pid")
+	register(stringFlag(func(p *runParser, v string) { p.spec.HostConfig.IpcMode = container.IpcMode(v) }), "--ipc")
+	register(stringFlag(func(p *runParser, v string) { p.spec.HostConfig.UTSMode = container.UTSMode(v) }), "--uts")
+	register(stringFlag(func(p *runParser, v string) {
+		p.spec.HostConfig.CgroupnsMode = container.CgroupnsMode(v)
+	}), "--cgroupns")
+	register(stringFlag(func(p *runParser, v string) {
+		p.spec.HostConfig.GroupAdd = append(p.spec.HostConfig.GroupAdd, v)
+	}), "--group-add")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		if v == "systempaths=unconfined" {
+			p.spec.HostConfig.MaskedPaths = []string{}
+			p.spec.HostConfig.ReadonlyPaths = []string{}
+			return nil
+		}
+		opt, err := loadSecurityOpt(v, p.baseDir)
+		if err != nil {
+			return err
+		}
+		p.spec.HostConfig.SecurityOpt = append(p.spec.HostConfig.SecurityOpt, opt)
+		return nil
+	}}, "--security-opt")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		name, alias, err := opts.ParseLink(v)
+		p.spec.HostConfig.Links = append(p.spec.HostConfig.Links, name+":"+alias)
+		return err
+	}}, "--link")
+	register(stringFlag(func(p *runParser, v string) {
+		p.spec.HostConfig.VolumesFrom = append(p.spec.HostConfig.VolumesFrom, v)
+	}), "--volumes-from")
+
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		cpus, err := strconv.ParseFloat(v, 64)
+		p.spec.HostConfig.NanoCPUs = int64(cpus * 1e9)
+		return err
+	}}, "--cpus")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		shares, err := strconv.ParseInt(v, 10, 64)
+		p.spec.HostConfig.CPUShares = shares
+		return err
+	}}, "-c", "--cpu-shares")
+	register(int64Flag(func(p *runParser, v int64) { p.spec.HostConfig.CPUPeriod = v }), "--cpu-period")
+	register(int64Flag(func(p *runParser, v int64) { p.spec.HostConfig.CPUQuota = v }), "--cpu-quota")
+	register(int64Flag(func(p *runParser, v int64) { p.spec.HostConfig.CPURealtimePeriod = v }), "--cpu-rt-period")
+	register(int64Flag(func(p *runParser, v int64) { p.spec.HostConfig.CPURealtimeRuntime = v }), "--cpu-rt-runtime")
+	register(stringFlag(func(p *runParser, v string) { p.spec.HostConfig.CpusetCpus = v }), "--cpuset-cpus")
+	register(stringFlag(func(p *runParser, v string) { p.spec.HostConfig.CpusetMems = v }), "--cpuset-mems")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		memory, err := units.RAMInBytes(v)
+		p.spec.HostConfig.Memory = memory
+		return err
+	}}, "-m", "--memory")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		reservation, err := units.RAMInBytes(v)
+		p.spec.HostConfig.MemoryReservation = reservation
+		return err
+	}}, "--memory-reservation")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		swap, err := parseMemorySwap(v)
+		p.spec.HostConfig.MemorySwap = swap
+		return err
+	}}, "--memory-swap")
+	register(int64Flag(func(p *runParser, v int64) { p.spec.HostConfig.MemorySwappiness = &v }), "--memory-swappiness")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		kernelMemory, err := units.RAMInBytes(v)
+		p.spec.HostConfig.KernelMemory = kernelMemory
+		return err
+	}}, "--kernel-memory")
+	register(int64Flag(func(p *runParser, v int64) { p.spec.HostConfig.PidsLimit = &v }), "--pids-limit")
+	register(boolFlag(func(p *runParser, v bool) { p.spec.HostConfig.OomKillDisable = &v }), "--oom-kill-disable")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		shmSize, err := units.RAMInBytes(v)
+		p.spec.HostConfig.ShmSize = shmSize
+		return err
+	}}, "--shm-size")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		ulimit, err := units.ParseUlimit(v)
+		if err != nil {
+			return err
+		}
+		p.spec.HostConfig.Ulimits = append(p.spec.HostConfig.Ulimits, ulimit)
+		return nil
+	}}, "--ulimit")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		key, value, found := strings.Cut(v, "=")
+		if !found {
+			return fmt.Errorf("invalid sysctl %q", v)
+		}
+		if p.spec.HostConfig.Sysctls == nil {
+			p.spec.HostConfig.Sysctls = make(map[string]string)
+		}
+		p.spec.HostConfig.Sysctls[key] = value
+		return nil
+	}}, "--sysctl")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		key, value, found := strings.Cut(v, "=")
+		if !found {
+			return fmt.Errorf("invalid storage option %q", v)
+		}
+		if p.spec.HostConfig.StorageOpt == nil {
+			p.spec.HostConfig.StorageOpt = make(map[string]string)
+		}
+		p.spec.HostConfig.StorageOpt[key] = value
+		return nil
+	}}, "--storage-opt")
+
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		weight, err := strconv.ParseUint(v, 10, 16)
+		p.spec.HostConfig.BlkioWeight = uint16(weight)
+		return err
+	}}, "--blkio-weight")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		device, err := parseWeightDevice(v)
+		if err != nil {
+			return err
+		}
+		p.spec.HostConfig.BlkioWeightDevice = append(p.spec.HostConfig.BlkioWeightDevice, device)
+		return nil
+	}}, "--blkio-weight-device")
+	register(throttleFlag(true, func(r *container.Resources) *[]*blkiodev.ThrottleDevice { return &r.BlkioDeviceReadBps }), "--device-read-bps")
+	register(throttleFlag(true, func(r *container.Resources) *[]*blkiodev.ThrottleDevice { return &r.BlkioDeviceWriteBps }), "--device-write-bps")
+	register(throttleFlag(false, func(r *container.Resources) *[]*blkiodev.ThrottleDevice { return &r.BlkioDeviceReadIOps }), "--device-read-iops")
+	register(throttleFlag(false, func(r *container.Resources) *[]*blkiodev.ThrottleDevice { return &r.BlkioDeviceWriteIOps }), "--device-write-iops")
+
+	register(stringFlag(func(p *runParser, v string) {
+		p.spec.HostConfig.NetworkMode = container.NetworkMode(v)
+	}), "--network", "--net")
+	register(stringFlag(func(p *runParser, v string) {
+		endpoint := p.endpointSettings()
+		endpoint.Aliases = append(endpoint.Aliases, v)
+	}), "--network-alias", "--net-alias")
+	register(stringFlag(func(p *runParser, v string) {
+		endpoint := p.endpointSettings()
+		if endpoint.IPAMConfig == nil {
+			endpoint.IPAMConfig = &network.EndpointIPAMConfig{}
+		}
+		endpoint.IPAMConfig.IPv4Address = v
+	}), "--ip")
+	register(stringFlag(func(p *runParser, v string) {
+		endpoint := p.endpointSettings()
+		if endpoint.IPAMConfig == nil {
+			endpoint.IPAMConfig = &network.EndpointIPAMConfig{}
+		}
+		endpoint.IPAMConfig.IPv6Address = v
+	}), "--ip6")
+	register(stringFlag(func(p *runParser, v string) { p.endpointSettings().MacAddress = v }), "--mac-address")
+	register(stringFlag(func(p *runParser, v string) {
+		p.spec.HostConfig.DNS = append(p.spec.HostConfig.DNS, v)
+	}), "--dns")
+	register(stringFlag(func(p *runParser, v string) {
+		p.spec.HostConfig.DNSSearch = append(p.spec.HostConfig.DNSSearch, v)
+	}), "--dns-search")
+	register(stringFlag(func(p *runParser, v string) {
+		p.spec.HostConfig.DNSOptions = append(p.spec.HostConfig.DNSOptions, v)
+	}), "--dns-option", "--dns-opt")
+
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		mappings, err := nat.ParsePortSpec(v)
+		if err != nil {
+			return err
+		}
+		if p.spec.Config.ExposedPorts == nil {
+			p.spec.Config.ExposedPorts = make(nat.PortSet)
+		}
+		if p.spec.HostConfig.PortBindings == nil {
+			p.spec.HostConfig.PortBindings = make(nat.PortMap)
+		}
+		for _, mapping := range mappings {
+			p.spec.Config.ExposedPorts[mapping.Port] = struct{}{}
+			p.spec.HostConfig.PortBindings[mapping.Port] = append(p.spec.HostConfig.PortBindings[mapping.Port], mapping.Binding)
+		}
+		return nil
+	}}, "-p", "--publish")
+	register(boolFlag(func(p *runParser, v bool) { p.spec.HostConfig.PublishAllPorts = v }), "-P", "--publish-all")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		proto, ports := nat.SplitProtoPort(v)
+		start, end, err := nat.ParsePortRange(ports)
+		if err != nil {
+			return err
+		}
+		if p.spec.Config.ExposedPorts == nil {
+			p.spec.Config.ExposedPorts = make(nat.PortSet)
+		}
+		for port := start; port <= end; port++ {
+			exposed, err := nat.NewPort(proto, strconv.FormatUint(port, 10))
+			if err != nil {
+				return err
+			}
+			p.spec.Config.ExposedPorts[exposed] = struct{}{}
+		}
+		return nil
+	}}, "--expose")
+
+	register(stringFlag(func(p *runParser, v string) {
+		source, target, found := strings.Cut(v, ":")
+		if !found {
+			if p.spec.Config.Volumes == nil {
+				p.spec.Config.Volumes = make(map[string]struct{})
+			}
+			p.spec.Config.Volumes[source] = struct{}{}
+			return
+		}
+		if strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~") {
+			source = expandPath(source, p.baseDir)
+		}
+		p.spec.HostConfig.Binds = append(p.spec.HostConfig.Binds, source+":"+target)
+	}), "-v", "--volume")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		m, err := parseMountSpec(v)
+		p.spec.HostConfig.Mounts = append(p.spec.HostConfig.Mounts, m)
+		return err
+	}}, "--mount")
+	register(stringFlag(func(p *runParser, v string) {
+		if p.spec.HostConfig.Tmpfs == nil {
+			p.spec.HostConfig.Tmpfs = make(map[string]string)
+		}
+		path, options, _ := strings.Cut(v, ":")
+		p.spec.HostConfig.Tmpfs[path] = options
+	}), "--tmpfs")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		device, err := parseDevice(v)
+		p.spec.HostConfig.Devices = append(p.spec.HostConfig.Devices, device)
+		return err
+	}}, "--device")
+
+	register(stringFlag(func(p *runParser, v string) { p.spec.HostConfig.LogConfig.Type = v }), "--log-driver")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		key, value, found := strings.Cut(v, "=")
+		if !found {
+			return fmt.Errorf("invalid log option %q, expected key=value", v)
+		}
+		if p.spec.HostConfig.LogConfig.Config == nil {
+			p.spec.HostConfig.LogConfig.Config = make(map[string]string)
+		}
+		p.spec.HostConfig.LogConfig.Config[key] = value
+		return nil
+	}}, "--log-opt")
+
+	register(stringFlag(func(p *runParser, v string) {
+		p.healthcheck().Test = []string{"CMD-SHELL", v}
+	}), "--health-cmd")
+	register(durationFlag(func(p *runParser, v time.Duration) { p.healthcheck().Interval = v }), "--health-interval")
+	register(durationFlag(func(p *runParser, v time.Duration) { p.healthcheck().Timeout = v }), "--health-timeout")
+	register(durationFlag(func(p *runParser, v time.Duration) { p.healthcheck().StartPeriod = v }), "--health-start-period")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		retries, err := strconv.Atoi(v)
+		p.healthcheck().Retries = retries
+		return err
+	}}, "--health-retries")
+	register(boolFlag(func(p *runParser, v bool) {
+		if v {
+			p.healthcheck().Test = []string{"NONE"}
+		}
+	}), "--no-healthcheck")
+	register(boolFlag(func(p *runParser, v bool) { p.spec.HostConfig.Init = &v }), "--init")
+	register(stringFlag(func(p *runParser, v string) { p.spec.Config.StopSignal = v }), "--stop-signal")
+	register(&runFlag{hasValue: true, apply: func(p *runParser, v string) error {
+		timeout, err := strconv.Atoi(v)
+		p.spec.Config.StopTimeout = &timeout
+		return err
+	}}, "--stop-timeout")
+}
+
+// readEnvFile 按照 docker --env-file 的规则读取环境变量文件
+func readEnvFile(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var env []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimLeftFunc(scanner.Text(), unicode.IsSpace)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		env = append(env, resolveEnv([]string{line})...)
+	}
+	return env, scanner.Err()
+}
+
+// legacyVolumeSourceRegexp 旧版本导出的脚本中命名卷的 source 为宿主机上的数据目录
+var legacyVolumeSourceRegexp = regexp.MustCompile(`^/var/lib/docker/volumes/([^/]+)/_data$`)
+
+// parseMountSpec 解析 --mount 参数
+func parseMountSpec(value string) (mount.Mount, error) {
+	fields, err := csv.NewReader(strings.NewReader(value)).Read()
+	if err != nil {
+		return mount.Mount{}, err
+	}
+
+	m := mount.Mount{Type: mount.TypeVolume}
+	for _, field := range fields {
+		key, val, hasValue := strings.Cut(field, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		switch key {
+		case "type":
+			m.Type = mount.Type(strings.ToLower(val))
+		case "source", "src":
+			m.Source = val
+		case "target", "dst", "destination":
+			m.Target = val
+		case "readonly", "ro":
+			m.ReadOnly = true
+			if hasValue {
+				if m.ReadOnly, err = strconv.ParseBool(val); err != nil {
+					return m, fmt.Errorf("invalid value for %s: %s", key, val)
+				}
+			}
+		case "consistency":
+			m.Consistency = mount.Consistency(val)
+		case "bind-propagation":
+			if m.BindOptions == nil {
+				m.BindOptions = &mount.BindOptions{}
+			}
+			m.BindOptions.Propagation = mount.Propagation(val)
+		case "bind-nonrecursive":
+			if m.BindOptions == nil {
+				m.BindOptions = &mount.BindOptions{}
+			}
+			m.BindOptions.NonRecursive = true
+			if hasValue {
+				if m.BindOptions.NonRecursive, err = strconv.ParseBool(val); err != nil {
+					return m, fmt.Errorf("invalid value for %s: %s", key, val)
+				}
+			}
+		case "volume-subpath":
+			if m.VolumeOptions == nil {
+				m.VolumeOptions = &mount.VolumeOptions{}
+			}
+			m.VolumeOptions.Subpath = val
+		case "volume-nocopy":
+			if m.VolumeOptions == nil {
+				m.VolumeOptions = &mount.VolumeOptions{}
+			}
+			m.VolumeOptions.NoCopy = true
+			if hasValue {
+				if m.VolumeOptions.NoCopy, err = strconv.ParseBool(val); err != nil {
+					return m, fmt.Errorf("invalid value for %s: %s", key, val)
+				}
+			}
+		case "volume-driver":
+			if m.VolumeOptions == nil {
+				m.VolumeOptions = &mount.VolumeOptions{}
+			}
+			if m.VolumeOptions.DriverConfig == nil {
+				m.VolumeOptions.DriverConfig = &mount.Driver{}
+			}
+			m.VolumeOptions.DriverConfig.Name = val
+		case "volume-opt":
+			if m.VolumeOptions == nil {
+				m.VolumeOptions = &mount.VolumeOptions{}
+			}
+			if m.VolumeOptions.DriverConfig == nil {
+				m.VolumeOptions.DriverConfig = &mount.Driver{}
+			}
+			if m.VolumeOptions.DriverConfig.Options == nil {
+				m.VolumeOptions.DriverConfig.Options = make(map[string]string)
+			}
+			optKey, optValue, _ := strings.Cut(val, "=")
+			m.VolumeOptions.DriverConfig.Options[optKey] = optValue
+		case "volume-label":
+			if m.VolumeOptions == nil {
+				m.VolumeOptions = &mount.VolumeOptions{}
+			}
+			if m.VolumeOptions.Labels == nil {
+				m.VolumeOptions.Labels = make(map[string]string)
+			}
+			labelKey, labelValue, _ := strings.Cut(val, "=")
+			m.VolumeOptions.Labels[labelKey] = labelValue
+		case "tmpfs-size":
+			if m.TmpfsOptions == nil {
+				m.TmpfsOptions = &mount.TmpfsOptions{}
+			}
+			if m.TmpfsOptions.SizeBytes, err = units.RAMInBytes(val); err != nil {
+				return m, fmt.Errorf("invalid value for %s: %s", key, val)
+			}
+		case "tmpfs-mode":
+			if m.TmpfsOptions == nil {
+				m.TmpfsOptions = &mount.TmpfsOptions{}
+			}
+			mode, err := strconv.ParseUint(val, 8, 32)
+			if err != nil {
+				return m, fmt.Errorf("invalid value for %s: %s", key, val)
+			}
+			m.TmpfsOptions.Mode = os.FileMode(mode)
+		default:
+			return m, fmt.Errorf("unexpected key %q in %q", key, field)
+		}
+	}
+
+	if m.Type == mount.TypeVolume {
+		if match := legacyVolumeSourceRegexp.FindStringSubmatch(m.Source); match != nil {
+			m.Source = match[1]
+		}
+	}
+	if m.Target == "" {
+		return m, fmt.Errorf("target is required")
+	}
+	return m, nil
+}
+
+// parseDevice 解析 --device 参数，格式为 host[:container[:permissions]]
+func parseDevice(value string) (container.DeviceMapping, error) {
+	parts := strings.Split(value, ":")
+	device := container.DeviceMapping{PathOnHost: parts[0], PathInContainer: parts[0], CgroupPermissions: "rwm"}
+	switch len(parts) {
+	case 1:
+	case 2:
+		if isDevicePermissions(parts[1]) {
+			device.CgroupPermissions = parts[1]
+		} else {
+			device.PathInContainer = parts[1]
+		}
+	case 3:
+		if !isDevicePermissions(parts[2]) {
+			return device, fmt.Errorf("invalid device permissions %q", parts[2])
+		}
+		device.PathInContainer = parts[1]
+		device.CgroupPermissions = parts[2]
+	default:
+		return device, fmt.Errorf("invalid device specification %q", value)
+	}
+	return device, nil
+}
+
+// isDevicePermissions 判断是否为 r、w、m 组成的设备权限
+func isDevicePermissions(s string) bool {
+	return s != "" && strings.Trim(s, "rwm") == ""
+}
@@ -0,0 +1,197 @@
+package dockercli
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+// loadTestScript 将脚本写入临时目录并通过 LoadCommands 导入
+func loadTestScript(t *testing.T, script string) ([]ContainerSpec, error) {
+	t.Helper()
+	return LoadCommands(writeTestFile(t, t.TempDir(), "docker_dump.sh", script))
+}
+
+func TestLoadCommandsFlags(t *testing.T) {
+	script := `#!/bin/sh
+# Container name: web
+docker run -d --name web \
+  --restart on-failure:3 \
+  -e 'A=hello world' -e B=1 \
+  --label 'x y=a b' \
+  -p 127.0.0.1:8080:80 -p8443:443/tcp \
+  -it \
+  --cap-add NET_ADMIN --privileged \
+  --memory=512m --cpus 1.5 \
+  -w /srv --user 1000:1000 \
+  --entrypoint /docker-entrypoint.sh \
+  nginx:latest nginx -g 'daemon off;'
+
+docker create --name db -v /srv/db:/var/lib/db postgres:16 # trailing comment
+docker start db
+`
+	specs, err := loadTestScript(t, script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("got %d containers, want 2", len(specs))
+	}
+
+	web := specs[0]
+	if web.Name != "web" || web.Config.Image != "nginx:latest" {
+		t.Errorf("name = %q, image = %q", web.Name, web.Config.Image)
+	}
+	if want := (container.RestartPolicy{Name: "on-failure", MaximumRetryCount: 3}); web.HostConfig.RestartPolicy != want {
+		t.Errorf("restart = %+v, want %+v", web.HostConfig.RestartPolicy, want)
+	}
+	if want := []string{"A=hello world", "B=1"}; !reflect.DeepEqual(web.Config.Env, want) {
+		t.Errorf("env = %q, want %q", web.Config.Env, want)
+	}
+	if want := map[string]string{"x y": "a b"}; !reflect.DeepEqual(web.Config.Labels, want) {
+		t.Errorf("labels = %q, want %q", web.Config.Labels, want)
+	}
+	wantPorts := nat.PortMap{
+		"80/tcp":  {{HostIP: "127.0.0.1", HostPort: "8080"}},
+		"443/tcp": {{HostPort: "8443"}},
+	}
+	if !reflect.DeepEqual(web.HostConfig.PortBindings, wantPorts) {
+		t.Errorf("ports = %v, want %v", web.HostConfig.PortBindings, wantPorts)
+	}
+	if !web.Config.Tty || !web.Config.OpenStdin {
+		t.Errorf("-it: tty = %v, stdin = %v", web.Config.Tty, web.Config.OpenStdin)
+	}
+	if !web.HostConfig.Privileged || !reflect.DeepEqual([]string(web.HostConfig.CapAdd), []string{"NET_ADMIN"}) {
+		t.Errorf("privileged = %v, cap-add = %q", web.HostConfig.Privileged, web.HostConfig.CapAdd)
+	}
+	if web.HostConfig.Memory != 512*1024*1024 || web.HostConfig.NanoCPUs != 1500000000 {
+		t.Errorf("memory = %d, nano cpus = %d", web.HostConfig.Memory, web.HostConfig.NanoCPUs)
+	}
+	if web.Config.WorkingDir != "/srv" || web.Config.User != "1000:1000" {
+		t.Errorf("workdir = %q, user = %q", web.Config.WorkingDir, web.Config.User)
+	}
+	if want := []string{"/docker-entrypoint.sh"}; !reflect.DeepEqual([]string(web.Config.Entrypoint), want) {
+		t.Errorf("entrypoint = %q, want %q", web.Config.Entrypoint, want)
+	}
+	if want := []string{"nginx", "-g", "daemon off;"}; !reflect.DeepEqual([]string(web.Config.Cmd), want) {
+		t.Errorf("cmd = %q, want %q", web.Config.Cmd, want)
+	}
+
+	db := specs[1]
+	if db.Name != "db" || !reflect.DeepEqual(db.HostConfig.Binds, []string{"/srv/db:/var/lib/db"}) {
+		t.Errorf("db: name = %q, binds = %q", db.Name, db.HostConfig.Binds)
+	}
+}
+
+func TestLoadCommandsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		errs   []string
+	}{
+		{
+			name:   "unknown long flag",
+			script: "docker run -d \\\n  --name web \\\n  --frobnicate 1 \\\n  nginx\n",
+			errs:   []string{"line 3: unknown flag --frobnicate"},
+		},
+		{
+			name:   "unknown short flag",
+			script: "\n\ndocker run -d -Q nginx\n",
+			errs:   []string{"line 3: unknown flag -Q"},
+		},
+		{
+			name:   "errors from several commands",
+			script: "docker run --bogus nginx\ndocker run -d \\\n  --restart sometimes nginx\n",
+			errs:   []string{"line 1: unknown flag --bogus", "line 3: --restart:"},
+		},
+		{
+			name:   "missing flag value",
+			script: "docker run -d --name",
+			errs:   []string{"line 1: flag --name needs an argument"},
+		},
+		{
+			name:   "missing image",
+			script: "docker run -d \\\n  --name web\n",
+			errs:   []string{"line 2: image is required"},
+		},
+		{
+			name:   "unterminated quote",
+			script: "docker run -e 'A=1 nginx\n",
+			errs:   []string{"docker_dump.sh"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTestScript(t, tt.script)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.errs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadCommandsSkipsOtherCommands(t *testing.T) {
+	script := "set -e\ndocker network create app\ndocker volume create data\ndocker run -d --name web nginx\n"
+	specs, err := loadTestScript(t, script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 1 || specs[0].Name != "web" {
+		t.Errorf("got %+v, want a single web container", specs)
+	}
+}
+
+func TestBuildRunCommandKeepsEntrypoint(t *testing.T) {
+	// Entrypoint 的底层数组有剩余容量时，拼接命令不能写入其中
+	backing := []string{"/bin/sh", "-c", "sentinel", "sentinel"}
+	web := testContainer("aaaaaaaaaaaa1111", "web")
+	web.Config.Entrypoint = backing[:2]
+	web.Config.Cmd = []string{"echo hi", "extra"}
+
+	graph, err := buildContainerGraph([]types.ContainerJSON{web})
+	if err != nil {
+		t.Fatal(err)
+	}
+	command := buildRunCommand(web, graph, "")
+	if want := []string{"-c", "echo hi", "extra"}; !reflect.DeepEqual(command.Args, want) {
+		t.Errorf("args = %q, want %q", command.Args, want)
+	}
+	if _, err := Containers2Quadlet([]types.ContainerJSON{web}, &ExportOptions{Format: "quadlet"}); err != nil {
+		t.Fatal(err)
+	}
+	if backing[2] != "sentinel" || backing[3] != "sentinel" {
+		t.Errorf("entrypoint backing array was modified: %q", backing)
+	}
+}
+
+func TestLoadCommandsTmpfs(t *testing.T) {
+	specs, err := loadTestScript(t, "docker run -d --tmpfs /run:size=64m,mode=1777 --tmpfs /tmp nginx\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"/run": "size=64m,mode=1777", "/tmp": ""}; !reflect.DeepEqual(specs[0].HostConfig.Tmpfs, want) {
+		t.Errorf("tmpfs = %q, want %q", specs[0].HostConfig.Tmpfs, want)
+	}
+}
+
+func TestLoadCommandsRelativeVolume(t *testing.T) {
+	// 相对路径以脚本所在目录为基准，而不是当前目录
+	dir := t.TempDir()
+	specs, err := LoadCommands(writeTestFile(t, dir, "docker_dump.sh", "docker run -d -v ./data:/data -v logs:/logs nginx\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(dir, "data") + ":/data", "logs:/logs"}; !reflect.DeepEqual(specs[0].HostConfig.Binds, want) {
+		t.Errorf("binds = %q, want %q", specs[0].HostConfig.Binds, want)
+	}
+}
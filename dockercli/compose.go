@@ -0,0 +1,994 @@
+package dockercli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/blkiodev"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
+	"github.com/fimreal/goutils/ezap"
+	"gopkg.in/yaml.v3"
+)
+
+// composeService compose 文件中单个服务的配置，只包含导入时支持的字段
+type composeService struct {
+	ContainerName string                   `yaml:"container_name"`
+	Image         string                   `yaml:"image"`
+	Command       composeCommand           `yaml:"command"`
+	Entrypoint    composeCommand           `yaml:"entrypoint"`
+	Environment   composeList              `yaml:"environment"`
+	EnvFile       composeEnvFiles          `yaml:"env_file"`
+	WorkingDir    string                   `yaml:"working_dir"`
+	Hostname      string                   `yaml:"hostname"`
+	User          string                   `yaml:"user"`
+	Privileged    bool                     `yaml:"privileged"`
+	Restart       string                   `yaml:"restart"`
+	Ports         []string                 `yaml:"ports"`
+	Volumes       []composeVolume          `yaml:"volumes"`
+	CapAdd        []string                 `yaml:"cap_add"`
+	CapDrop       []string                 `yaml:"cap_drop"`
+	OomScoreAdj   int                      `yaml:"oom_score_adj"`
+	UsernsMode    string                   `yaml:"userns_mode"`
+	Ipc           string                   `yaml:"ipc"`
+	Labels        composeList              `yaml:"labels"`
+	ExtraHosts    []string                 `yaml:"extra_hosts"`
+	DNS           composeCommand           `yaml:"dns"`
+	Logging       *composeLogging          `yaml:"logging"`
+	Pid           string                   `yaml:"pid"`
+	Links         []string                 `yaml:"links"`
+	ExternalLinks []string                 `yaml:"external_links"`
+	VolumesFrom   []string                 `yaml:"volumes_from"`
+	DependsOn     yaml.Node                `yaml:"depends_on"`
+	NetworkMode   string                   `yaml:"network_mode"`
+	Networks      composeNetworks          `yaml:"networks"`
+	Healthcheck   *composeHealth           `yaml:"healthcheck"`
+	Init          *bool                    `yaml:"init"`
+	StopSignal    string                   `yaml:"stop_signal"`
+	StopGrace     string                   `yaml:"stop_grace_period"`
+	Cpus          string                   `yaml:"cpus"`
+	CPUShares     int64                    `yaml:"cpu_shares"`
+	CPUPeriod     int64                    `yaml:"cpu_period"`
+	CPUQuota      int64                    `yaml:"cpu_quota"`
+	CPURtPeriod   int64                    `yaml:"cpu_rt_period"`
+	CPURtRuntime  int64                    `yaml:"cpu_rt_runtime"`
+	Cpuset        string                   `yaml:"cpuset"`
+	MemLimit      string                   `yaml:"mem_limit"`
+	MemReserve    string                   `yaml:"mem_reservation"`
+	MemswapLimit  string                   `yaml:"memswap_limit"`
+	MemSwappiness *int64                   `yaml:"mem_swappiness"`
+	PidsLimit     *int64                   `yaml:"pids_limit"`
+	BlkioConfig   *composeBlkio            `yaml:"blkio_config"`
+	SecurityOpt   []string                 `yaml:"security_opt"`
+	GroupAdd      []string                 `yaml:"group_add"`
+	Uts           string                   `yaml:"uts"`
+	Cgroup        string                   `yaml:"cgroup"`
+	OomKillOff    bool                     `yaml:"oom_kill_disable"`
+	ShmSize       string                   `yaml:"shm_size"`
+	StorageOpt    map[string]string        `yaml:"storage_opt"`
+	Ulimits       map[string]composeUlimit `yaml:"ulimits"`
+	Sysctls       composeList              `yaml:"sysctls"`
+	Tmpfs         composeCommand           `yaml:"tmpfs"`
+	Extra         map[string]any           `yaml:",inline"`
+}
+
+// composeHealth 服务的健康检查配置
+type composeHealth struct {
+	Test        composeHealthTest `yaml:"test"`
+	Interval    string            `yaml:"interval"`
+	Timeout     string            `yaml:"timeout"`
+	StartPeriod string            `yaml:"start_period"`
+	Retries     int               `yaml:"retries"`
+	Disable     bool              `yaml:"disable"`
+}
+
+// composeHealthTest 健康检查命令，字符串形式等同于 CMD-SHELL
+type composeHealthTest []string
+
+func (t *composeHealthTest) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*t = []string{"CMD-SHELL", value.Value}
+		return nil
+	}
+	var test []string
+	if err := value.Decode(&test); err != nil {
+		return err
+	}
+	*t = test
+	return nil
+}
+
+// toHealthConfig 转换为容器的健康检查配置
+func (h composeHealth) toHealthConfig() (*container.HealthConfig, error) {
+	if h.Disable {
+		return &container.HealthConfig{Test: []string{"NONE"}}, nil
+	}
+	healthcheck := &container.HealthConfig{Test: h.Test, Retries: h.Retries}
+	for _, d := range []struct {
+		value  string
+		target *time.Duration
+	}{
+		{h.Interval, &healthcheck.Interval},
+		{h.Timeout, &healthcheck.Timeout},
+		{h.StartPeriod, &healthcheck.StartPeriod},
+	} {
+		if d.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid healthcheck duration %q: %w", d.value, err)
+		}
+		*d.target = duration
+	}
+	return healthcheck, nil
+}
+
+// composeBlkio 服务的 blkio_config 配置
+type composeBlkio struct {
+	Weight          uint16                `yaml:"weight"`
+	WeightDevice    []composeWeightDevice `yaml:"weight_device"`
+	DeviceReadBps   []composeThrottle     `yaml:"device_read_bps"`
+	DeviceWriteBps  []composeThrottle     `yaml:"device_write_bps"`
+	DeviceReadIOps  []composeThrottle     `yaml:"device_read_iops"`
+	DeviceWriteIOps []composeThrottle     `yaml:"device_write_iops"`
+}
+
+type composeWeightDevice struct {
+	Path   string `yaml:"path"`
+	Weight uint16 `yaml:"weight"`
+}
+
+type composeThrottle struct {
+	Path string `yaml:"path"`
+	Rate string `yaml:"rate"`
+}
+
+// applyResources 将服务的资源限制写入容器配置
+func (s composeService) applyResources(resources *container.Resources) error {
+	if s.Cpus != "" {
+		cpus, err := strconv.ParseFloat(s.Cpus, 64)
+		if err != nil {
+			return fmt.Errorf("invalid cpus %q: %w", s.Cpus, err)
+		}
+		resources.NanoCPUs = int64(cpus * 1e9)
+	}
+	resources.CPUShares = s.CPUShares
+	resources.CPUPeriod = s.CPUPeriod
+	resources.CPUQuota = s.CPUQuota
+	resources.CPURealtimePeriod = s.CPURtPeriod
+	resources.CPURealtimeRuntime = s.CPURtRuntime
+	resources.CpusetCpus = s.Cpuset
+	resources.MemorySwappiness = s.MemSwappiness
+	resources.PidsLimit = s.PidsLimit
+
+	for _, m := range []struct {
+		key    string
+		value  string
+		target *int64
+		parse  func(string) (int64, error)
+	}{
+		{"mem_limit", s.MemLimit, &resources.Memory, units.RAMInBytes},
+		{"mem_reservation", s.MemReserve, &resources.MemoryReservation, units.RAMInBytes},
+		{"memswap_limit", s.MemswapLimit, &resources.MemorySwap, parseMemorySwap},
+	} {
+		if m.value == "" {
+			continue
+		}
+		size, err := m.parse(m.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", m.key, m.value, err)
+		}
+		*m.target = size
+	}
+
+	if s.BlkioConfig == nil {
+		return nil
+	}
+	resources.BlkioWeight = s.BlkioConfig.Weight
+	for _, device := range s.BlkioConfig.WeightDevice {
+		resources.BlkioWeightDevice = append(resources.BlkioWeightDevice, &blkiodev.WeightDevice{Path: device.Path, Weight: device.Weight})
+	}
+	for _, throttle := range []struct {
+		devices []composeThrottle
+		target  *[]*blkiodev.ThrottleDevice
+		bytes   bool
+	}{
+		{s.BlkioConfig.DeviceReadBps, &resources.BlkioDeviceReadBps, true},
+		{s.BlkioConfig.DeviceWriteBps, &resources.BlkioDeviceWriteBps, true},
+		{s.BlkioConfig.DeviceReadIOps, &resources.BlkioDeviceReadIOps, false},
+		{s.BlkioConfig.DeviceWriteIOps, &resources.BlkioDeviceWriteIOps, false},
+	} {
+		for _, device := range throttle.devices {
+			parsed, err := parseThrottleDevice(device.Path+":"+device.Rate, throttle.bytes)
+			if err != nil {
+				return err
+			}
+			*throttle.target = append(*throttle.target, parsed)
+		}
+	}
+	return nil
+}
+
+// composeUlimit 兼容单个值和 soft、hard 映射两种写法，单个值表示软限制和硬限制相同
+type composeUlimit struct {
+	Soft int64 `yaml:"soft"`
+	Hard int64 `yaml:"hard"`
+}
+
+func (u *composeUlimit) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var limit int64
+		if err := value.Decode(&limit); err != nil {
+			return err
+		}
+		u.Soft, u.Hard = limit, limit
+		return nil
+	}
+	type plain composeUlimit
+	return value.Decode((*plain)(u))
+}
+
+// composeVolume 服务的挂载配置，兼容短语法字符串和长语法映射，短语法保存在 Short 中
+type composeVolume struct {
+	Short       string              `yaml:"-"`
+	Type        string              `yaml:"type"`
+	Source      string              `yaml:"source"`
+	Target      string              `yaml:"target"`
+	ReadOnly    bool                `yaml:"read_only"`
+	Consistency string              `yaml:"consistency"`
+	Bind        *composeBindOptions `yaml:"bind"`
+	Volume      *struct {
+		NoCopy  bool   `yaml:"nocopy"`
+		Subpath string `yaml:"subpath"`
+	} `yaml:"volume"`
+	Tmpfs *struct {
+		Size string `yaml:"size"`
+		Mode uint32 `yaml:"mode"`
+	} `yaml:"tmpfs"`
+}
+
+// composeBindOptions 长语法中 bind 挂载的选项
+type composeBindOptions struct {
+	Propagation    string `yaml:"propagation"`
+	CreateHostPath bool   `yaml:"create_host_path"`
+	Recursive      string `yaml:"recursive"`
+	SELinux        string `yaml:"selinux"`
+}
+
+func (v *composeVolume) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		v.Short = value.Value
+		return nil
+	}
+	type plain composeVolume
+	return value.Decode((*plain)(v))
+}
+
+// toMount 将长语法的挂载转换为创建容器时的 Mount，相对路径以 baseDir 为基准
+func (v composeVolume) toMount(baseDir string, volumeNames composeNames) (mount.Mount, error) {
+	m := mount.Mount{Type: mount.Type(v.Type), Source: v.Source, Target: v.Target, ReadOnly: v.ReadOnly, Consistency: mount.Consistency(v.Consistency)}
+	switch m.Type {
+	case mount.TypeBind:
+		if strings.HasPrefix(m.Source, ".") || strings.HasPrefix(m.Source, "~") {
+			m.Source = expandPath(m.Source, baseDir)
+		}
+	case mount.TypeVolume:
+		if m.Source != "" {
+			m.Source = volumeNames.resolve(m.Source)
+		}
+	}
+	if v.Bind != nil {
+		m.BindOptions = &mount.BindOptions{
+			Propagation:      mount.Propagation(v.Bind.Propagation),
+			NonRecursive:     v.Bind.Recursive == "disabled",
+			CreateMountpoint: v.Bind.CreateHostPath,
+		}
+	}
+	if v.Volume != nil {
+		m.VolumeOptions = &mount.VolumeOptions{NoCopy: v.Volume.NoCopy, Subpath: v.Volume.Subpath}
+	}
+	if v.Tmpfs != nil {
+		m.TmpfsOptions = &mount.TmpfsOptions{Mode: os.FileMode(v.Tmpfs.Mode)}
+		if v.Tmpfs.Size != "" {
+			size, err := units.RAMInBytes(v.Tmpfs.Size)
+			if err != nil {
+				return m, fmt.Errorf("invalid tmpfs size %q for %s", v.Tmpfs.Size, v.Target)
+			}
+			m.TmpfsOptions.SizeBytes = size
+		}
+	}
+	if m.Target == "" {
+		return m, fmt.Errorf("volume %q: target is required", v.Source)
+	}
+	return m, nil
+}
+
+// composeEnvFile 服务的环境变量文件，format 为 raw 时按照 docker --env-file 的规则读取
+type composeEnvFile struct {
+	Path     string `yaml:"path"`
+	Required *bool  `yaml:"required"`
+	Format   string `yaml:"format"`
+}
+
+func (f *composeEnvFile) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		f.Path = value.Value
+		return nil
+	}
+	type plain composeEnvFile
+	return value.Decode((*plain)(f))
+}
+
+// composeEnvFiles 兼容单个文件和列表两种写法
+type composeEnvFiles []composeEnvFile
+
+func (f *composeEnvFiles) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*f = composeEnvFiles{{Path: value.Value}}
+		return nil
+	}
+	var files []composeEnvFile
+	if err := value.Decode(&files); err != nil {
+		return err
+	}
+	*f = files
+	return nil
+}
+
+// loadEnv 读取 env_file 中的环境变量，environment 中的同名变量覆盖文件中的值，相对路径以 baseDir 为基准
+func (f composeEnvFiles) loadEnv(environment []string, baseDir string) ([]string, error) {
+	var env []string
+	for _, file := range f {
+		path := file.Path
+		if !filepath.IsAbs(path) {
+			path = expandPath(path, baseDir)
+		}
+		var fileEnv []string
+		var err error
+		if file.Format == "raw" {
+			fileEnv, err = readEnvFile(path)
+		} else {
+			fileEnv, err = readDotEnv(path)
+		}
+		if os.IsNotExist(err) && file.Required != nil && !*file.Required {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("env_file: %w", err)
+		}
+		env = mergeEnv(env, fileEnv)
+	}
+	return mergeEnv(env, environment), nil
+}
+
+// readDotEnv 按照 compose 默认的 .env 规则读取环境变量文件，支持 export 前缀、单双引号和行尾注释，
+// 双引号中支持转义和变量替换，不支持跨行的值
+func readDotEnv(filename string) ([]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var env []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		name, value, found := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !found {
+			env = append(env, resolveEnv([]string{name})...)
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			value = interpolate(dotEnvUnescaper.Replace(value[1 : len(value)-1]))
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+			value = interpolate(value)
+		}
+		env = append(env, name+"="+value)
+	}
+	return env, nil
+}
+
+// dotEnvUnescaper 处理 .env 双引号中的转义
+var dotEnvUnescaper = strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`, `\$`, "$$")
+
+// mergeEnv 合并环境变量，overrides 中的同名变量替换 base 中的值
+func mergeEnv(base, overrides []string) []string {
+	merged := append([]string(nil), base...)
+	for _, env := range overrides {
+		name, _, _ := strings.Cut(env, "=")
+		replaced := false
+		for i, existing := range merged {
+			if existingName, _, _ := strings.Cut(existing, "="); existingName == name {
+				merged[i] = env
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, env)
+		}
+	}
+	return merged
+}
+
+type composeLogging struct {
+	Driver  string            `yaml:"driver"`
+	Options map[string]string `yaml:"options"`
+}
+
+// composeServiceNetwork 服务连接到某个网络时的配置
+type composeServiceNetwork struct {
+	Aliases     []string `yaml:"aliases"`
+	IPv4Address string   `yaml:"ipv4_address"`
+	IPv6Address string   `yaml:"ipv6_address"`
+	MacAddress  string   `yaml:"mac_address"`
+}
+
+// composeNetworks 兼容列表和映射两种写法的服务网络配置
+type composeNetworks map[string]*composeServiceNetwork
+
+func (n *composeNetworks) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		var list []string
+		if err := value.Decode(&list); err != nil {
+			return err
+		}
+		*n = make(composeNetworks)
+		for _, name := range list {
+			(*n)[name] = nil
+		}
+		return nil
+	}
+	var m map[string]*composeServiceNetwork
+	if err := value.Decode(&m); err != nil {
+		return err
+	}
+	*n = m
+	return nil
+}
+
+// composeResource 顶级 networks 和 volumes 中的定义，只关心真实名称
+type composeResource struct {
+	Name string `yaml:"name"`
+}
+
+// composeNames 顶级定义中的键到真实名称的映射
+type composeNames map[string]string
+
+// resolve 返回键对应的真实名称，未定义 name 时使用键本身
+func (n composeNames) resolve(key string) string {
+	if name, ok := n[key]; ok && name != "" {
+		return name
+	}
+	return key
+}
+
+// decodeComposeNames 解析顶级 networks 或 volumes 中的名称
+func decodeComposeNames(node yaml.Node) (composeNames, error) {
+	names := make(composeNames)
+	if node.Kind != yaml.MappingNode {
+		return names, nil
+	}
+	var resources map[string]*composeResource
+	if err := node.Decode(&resources); err != nil {
+		return nil, err
+	}
+	for key, resource := range resources {
+		if resource != nil {
+			names[key] = resource.Name
+		}
+	}
+	return names, nil
+}
+
+// composeCommand 兼容字符串和列表两种写法
+type composeCommand []string
+
+func (c *composeCommand) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		words, err := splitShellWords(value.Value)
+		if err != nil {
+			return err
+		}
+		*c = words
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*c = list
+	return nil
+}
+
+// composeList 兼容 KEY=VALUE 列表和 KEY: VALUE 映射两种写法，统一转换为 KEY=VALUE 列表
+type composeList []string
+
+func (l *composeList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		var list []string
+		if err := value.Decode(&list); err != nil {
+			return err
+		}
+		*l = list
+		return nil
+	}
+	var m map[string]*string
+	if err := value.Decode(&m); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if m[key] == nil {
+			*l = append(*l, key)
+		} else {
+			*l = append(*l, key+"="+*m[key])
+		}
+	}
+	return nil
+}
+
+// composeTopLevelKeys compose 文件中不是服务的顶级字段
+var composeTopLevelKeys = map[string]bool{
+	"version":  true,
+	"name":     true,
+	"services": true,
+	"networks": true,
+	"volumes":  true,
+	"configs":  true,
+	"secrets":  true,
+}
+
+// LoadCompose 解析 Containers2Compose 生成的 compose 文件，path 为目录时读取其中所有 yml/yaml 文件
+func LoadCompose(path string) ([]ContainerSpec, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return loadComposeFile(path)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var specs []ContainerSpec
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+		fileSpecs, err := loadComposeFile(filepath.Join(path, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, fileSpecs...)
+	}
+	return specs, nil
+}
+
+// loadComposeFile 解析单个 compose 文件
+func loadComposeFile(filename string) ([]ContainerSpec, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	interpolateNode(&root)
+	var document map[string]yaml.Node
+	if err := root.Decode(&document); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	services := make(map[string]composeService)
+	if node, ok := document["services"]; ok && node.Kind == yaml.MappingNode {
+		if err := node.Decode(&services); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+	} else {
+		// 旧版本导出的文件中服务没有缩进在 services 下，而是直接位于顶层
+		for key, node := range document {
+			if composeTopLevelKeys[key] || strings.HasPrefix(key, "x-") {
+				continue
+			}
+			var service composeService
+			if err := node.Decode(&service); err != nil {
+				return nil, fmt.Errorf("%s: service %s: %w", filename, key, err)
+			}
+			services[key] = service
+		}
+	}
+
+	networkNames, err := decodeComposeNames(document["networks"])
+	if err != nil {
+		return nil, fmt.Errorf("%s: networks: %w", filename, err)
+	}
+	volumeNames, err := decodeComposeNames(document["volumes"])
+	if err != nil {
+		return nil, fmt.Errorf("%s: volumes: %w", filename, err)
+	}
+
+	// 按服务名排序，保证导入顺序稳定
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var specs []ContainerSpec
+	for _, name := range names {
+		spec, err := services[name].toSpec(name, filepath.Dir(filename), networkNames, volumeNames)
+		if err != nil {
+			return nil, fmt.Errorf("%s: service %s: %w", filename, name, err)
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// toSpec 将 compose 服务转换为创建容器的参数，相对路径的挂载以 baseDir 为基准，
+// networkNames 和 volumeNames 用于将顶级定义的键转换为真实名称
+func (s composeService) toSpec(serviceName, baseDir string, networkNames, volumeNames composeNames) (ContainerSpec, error) {
+	if s.Image == "" {
+		return ContainerSpec{}, fmt.Errorf("image is required")
+	}
+	for key := range s.Extra {
+		if !strings.HasPrefix(key, "x-") {
+			ezap.Warnf("service %s: ignoring unsupported key %q", serviceName, key)
+		}
+	}
+
+	name := s.ContainerName
+	if name == "" {
+		name = serviceName
+	}
+
+	env, err := s.EnvFile.loadEnv(resolveEnv(s.Environment), baseDir)
+	if err != nil {
+		return ContainerSpec{}, err
+	}
+
+	config := &container.Config{
+		Image:      s.Image,
+		Cmd:        []string(s.Command),
+		Entrypoint: []string(s.Entrypoint),
+		Env:        env,
+		WorkingDir: s.WorkingDir,
+		Hostname:   s.Hostname,
+		User:       s.User,
+		Labels:     parseLabels(s.Labels),
+		StopSignal: s.StopSignal,
+	}
+	hostConfig := &container.HostConfig{
+		Privileged:   s.Privileged,
+		CapAdd:       s.CapAdd,
+		CapDrop:      s.CapDrop,
+		OomScoreAdj:  s.OomScoreAdj,
+		UsernsMode:   container.UsernsMode(s.UsernsMode),
+		IpcMode:      container.IpcMode(serviceRef(s.Ipc)),
+		PidMode:      container.PidMode(serviceRef(s.Pid)),
+		Links:        append(s.Links, s.ExternalLinks...),
+		ExtraHosts:   s.ExtraHosts,
+		DNS:          []string(s.DNS),
+		Init:         s.Init,
+		GroupAdd:     s.GroupAdd,
+		UTSMode:      container.UTSMode(s.Uts),
+		CgroupnsMode: container.CgroupnsMode(s.Cgroup),
+	}
+
+	if s.OomKillOff {
+		hostConfig.OomKillDisable = &s.OomKillOff
+	}
+	if s.ShmSize != "" {
+		shmSize, err := units.RAMInBytes(s.ShmSize)
+		if err != nil {
+			return ContainerSpec{}, fmt.Errorf("invalid shm_size %q: %w", s.ShmSize, err)
+		}
+		hostConfig.ShmSize = shmSize
+	}
+	hostConfig.StorageOpt = s.StorageOpt
+	hostConfig.Sysctls = parseLabels(s.Sysctls)
+	for _, name := range sortedKeys(s.Ulimits) {
+		ulimit := s.Ulimits[name]
+		hostConfig.Ulimits = append(hostConfig.Ulimits, &container.Ulimit{Name: name, Soft: ulimit.Soft, Hard: ulimit.Hard})
+	}
+	for _, tmpfs := range s.Tmpfs {
+		path, options, _ := strings.Cut(tmpfs, ":")
+		if hostConfig.Tmpfs == nil {
+			hostConfig.Tmpfs = make(map[string]string)
+		}
+		hostConfig.Tmpfs[path] = options
+	}
+
+	for _, opt := range s.SecurityOpt {
+		if opt == "systempaths=unconfined" {
+			hostConfig.MaskedPaths = []string{}
+			hostConfig.ReadonlyPaths = []string{}
+			continue
+		}
+		opt, err := loadSecurityOpt(opt, baseDir)
+		if err != nil {
+			return ContainerSpec{}, err
+		}
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, opt)
+	}
+
+	if err := s.applyResources(&hostConfig.Resources); err != nil {
+		return ContainerSpec{}, err
+	}
+
+	if s.Healthcheck != nil {
+		healthcheck, err := s.Healthcheck.toHealthConfig()
+		if err != nil {
+			return ContainerSpec{}, err
+		}
+		config.Healthcheck = healthcheck
+	}
+
+	if s.StopGrace != "" {
+		grace, err := time.ParseDuration(s.StopGrace)
+		if err != nil {
+			return ContainerSpec{}, fmt.Errorf("invalid stop_grace_period %q: %w", s.StopGrace, err)
+		}
+		timeout := int(grace.Seconds())
+		config.StopTimeout = &timeout
+	}
+
+	if s.Restart != "" {
+		policy, err := parseRestartPolicy(s.Restart)
+		if err != nil {
+			return ContainerSpec{}, err
+		}
+		hostConfig.RestartPolicy = policy
+	}
+
+	if len(s.Ports) > 0 {
+		exposed, bindings, err := nat.ParsePortSpecs(s.Ports)
+		if err != nil {
+			return ContainerSpec{}, err
+		}
+		config.ExposedPorts = exposed
+		hostConfig.PortBindings = bindings
+	}
+
+	for _, volume := range s.Volumes {
+		if volume.Short == "" {
+			if volume.Bind != nil && volume.Bind.SELinux != "" {
+				// Mount 不支持 SELinux 标签，和短语法一样通过 Binds 创建
+				volume.Short = volume.Source + ":" + volume.Target + ":" + volume.Bind.SELinux
+				if volume.ReadOnly {
+					volume.Short += ",ro"
+				}
+			} else {
+				m, err := volume.toMount(baseDir, volumeNames)
+				if err != nil {
+					return ContainerSpec{}, err
+				}
+				hostConfig.Mounts = append(hostConfig.Mounts, m)
+				continue
+			}
+		}
+		source, target, found := strings.Cut(volume.Short, ":")
+		if !found {
+			// 匿名卷
+			if config.Volumes == nil {
+				config.Volumes = make(map[string]struct{})
+			}
+			config.Volumes[source] = struct{}{}
+			continue
+		}
+		if strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~") {
+			source = expandPath(source, baseDir)
+		} else if !strings.HasPrefix(source, "/") {
+			source = volumeNames.resolve(source)
+		}
+		hostConfig.Binds = append(hostConfig.Binds, source+":"+target)
+	}
+
+	for _, from := range s.VolumesFrom {
+		hostConfig.VolumesFrom = append(hostConfig.VolumesFrom, strings.TrimPrefix(from, "container:"))
+	}
+
+	if s.Logging != nil {
+		hostConfig.LogConfig = container.LogConfig{Type: s.Logging.Driver, Config: s.Logging.Options}
+	}
+
+	networkingConfig := &network.NetworkingConfig{}
+	if s.NetworkMode != "" {
+		hostConfig.NetworkMode = container.NetworkMode(serviceRef(s.NetworkMode))
+	} else if len(s.Networks) > 0 {
+		keys := make([]string, 0, len(s.Networks))
+		for key := range s.Networks {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		networkingConfig.EndpointsConfig = make(map[string]*network.EndpointSettings)
+		for _, key := range keys {
+			endpoint := &network.EndpointSettings{}
+			if settings := s.Networks[key]; settings != nil {
+				endpoint.Aliases = settings.Aliases
+				endpoint.MacAddress = settings.MacAddress
+				if settings.IPv4Address != "" || settings.IPv6Address != "" {
+					endpoint.IPAMConfig = &network.EndpointIPAMConfig{IPv4Address: settings.IPv4Address, IPv6Address: settings.IPv6Address}
+				}
+			}
+			networkingConfig.EndpointsConfig[networkNames.resolve(key)] = endpoint
+		}
+		hostConfig.NetworkMode = container.NetworkMode(networkNames.resolve(keys[0]))
+	}
+
+	return ContainerSpec{
+		Name:             name,
+		Config:           config,
+		HostConfig:       hostConfig,
+		NetworkingConfig: networkingConfig,
+	}, nil
+}
+
+// serviceRef 导出时服务名与容器名相同，将 service:<name> 转换为 container:<name>
+func serviceRef(mode string) string {
+	if service, found := strings.CutPrefix(mode, "service:"); found {
+		return "container:" + service
+	}
+	return mode
+}
+
+// expandPath 展开 compose 中以 . 或 ~ 开头的相对路径
+func expandPath(path, baseDir string) string {
+	if rest, found := strings.CutPrefix(path, "~"); found {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+		return path
+	}
+	abs, err := filepath.Abs(filepath.Join(baseDir, path))
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+// resolveEnv 与 docker cli 一致，没有值的环境变量从当前环境中读取，不存在则忽略
+func resolveEnv(env []string) []string {
+	var resolved []string
+	for _, e := range env {
+		if strings.Contains(e, "=") {
+			resolved = append(resolved, e)
+			continue
+		}
+		if value, ok := os.LookupEnv(e); ok {
+			resolved = append(resolved, e+"="+value)
+		}
+	}
+	return resolved
+}
+
+// parseLabels 将 KEY=VALUE 列表转换为 label 映射
+func parseLabels(labels []string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(labels))
+	for _, label := range labels {
+		key, value, _ := strings.Cut(label, "=")
+		m[key] = value
+	}
+	return m
+}
+
+// composeScalar 生成 compose 中的字符串，$ 需要写成 $$ 以避免被当作变量替换
+func composeScalar(s string) string {
+	return yamlScalar(strings.ReplaceAll(s, "$", "$$"))
+}
+
+// composeQuote 生成 compose 中双引号形式的字符串
+func composeQuote(s string) string {
+	return yamlQuote(strings.ReplaceAll(s, "$", "$$"))
+}
+
+// composeFlowList 生成 compose 中 [a, b] 形式的列表
+func composeFlowList(items []string) string {
+	escaped := make([]string, len(items))
+	for i, item := range items {
+		escaped[i] = strings.ReplaceAll(item, "$", "$$")
+	}
+	return yamlFlowList(escaped)
+}
+
+// interpolate 与 compose 一致地替换 $VAR、${VAR}、${VAR:-default}、${VAR-default}，$$ 表示 $
+func interpolate(s string) string {
+	return os.Expand(s, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		if key, def, found := strings.Cut(name, ":-"); found {
+			if value := os.Getenv(key); value != "" {
+				return value
+			}
+			return def
+		}
+		if key, def, found := strings.Cut(name, "-"); found {
+			if value, ok := os.LookupEnv(key); ok {
+				return value
+			}
+			return def
+		}
+		return os.Getenv(name)
+	})
+}
+
+// interpolateNode 对 yaml 节点中的所有字符串做变量替换
+func interpolateNode(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode {
+		node.Value = interpolate(node.Value)
+		return
+	}
+	for _, child := range node.Content {
+		interpolateNode(child)
+	}
+}
+
+// yamlPlainRegexp 不需要加引号的 yaml 字符串
+var yamlPlainRegexp = regexp.MustCompile(`^[A-Za-z_/.][A-Za-z0-9_/.@%+=,:-]*$`)
+
+// yamlScalar 在必要时为 yaml 字符串加上引号
+func yamlScalar(s string) string {
+	switch strings.ToLower(s) {
+	case "y", "n", "yes", "no", "on", "off", "true", "false", "null":
+		return yamlQuote(s)
+	}
+	if yamlPlainRegexp.MatchString(s) && !strings.HasSuffix(s, ":") && !strings.Contains(s, ": ") {
+		return s
+	}
+	return yamlQuote(s)
+}
+
+// yamlQuote 返回双引号形式的 yaml 字符串，JSON 字符串同时也是合法的 yaml 双引号字符串
+func yamlQuote(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// yamlFlowList 生成 [a, b] 形式的 yaml 列表
+func yamlFlowList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = yamlQuote(item)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
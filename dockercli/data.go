@@ -0,0 +1,196 @@
+package dockercli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/errdefs"
+	"github.com/fimreal/goutils/ezap"
+)
+
+// DataDir 数据归档在输出目录中的子目录
+const DataDir = "data"
+
+// dataManifestFile 数据归档清单的文件名
+const dataManifestFile = "manifest.json"
+
+// DataManifest 导出的数据归档清单
+type DataManifest struct {
+	Created  string        `json:"created"`
+	Archives []DataArchive `json:"archives"`
+}
+
+// DataArchive 单个挂载的数据归档
+type DataArchive struct {
+	Container   string `json:"container"`
+	Type        string `json:"type"`
+	Source      string `json:"source,omitempty"` // bind 挂载的宿主机路径或命名卷名称，匿名卷为空
+	Destination string `json:"destination"`
+	File        string `json:"file"` // 归档文件名，相对于数据目录
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+}
+
+// ExportData 将容器中 bind 挂载和卷的内容通过 CopyFromContainer 写入 dir 下的 tar 文件，
+// 每个挂载一个文件，并生成记录大小和校验和的清单
+func (d *DockerClient) ExportData(containersJSON []types.ContainerJSON, dir string) (*DataManifest, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	manifest := &DataManifest{Created: time.Now().Format(time.RFC3339)}
+	for _, containerJSON := range containersJSON {
+		cname := strings.TrimPrefix(containerJSON.Name, "/")
+		if containerJSON.State != nil && containerJSON.State.Running {
+			ezap.Warnf("%s is running, the archived data may be inconsistent, stop it before exporting", cname)
+		}
+		for _, mountPoint := range containerJSON.Mounts {
+			if mountPoint.Type != mount.TypeBind && mountPoint.Type != mount.TypeVolume {
+				continue
+			}
+			archive := DataArchive{
+				Container:   cname,
+				Type:        string(mountPoint.Type),
+				Source:      mountSource(mountPoint),
+				Destination: mountPoint.Destination,
+				File:        dataArchiveName(cname, mountPoint.Destination),
+			}
+			ezap.Infof("Archiving %s:%s to %s", cname, mountPoint.Destination, archive.File)
+			if err := d.archiveMount(containerJSON.ID, filepath.Join(dir, archive.File), &archive); err != nil {
+				return nil, fmt.Errorf("error archiving %s:%s: %w", cname, mountPoint.Destination, err)
+			}
+			manifest.Archives = append(manifest.Archives, archive)
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return manifest, os.WriteFile(filepath.Join(dir, dataManifestFile), append(data, '\n'), 0644)
+}
+
+// dataArchiveName 返回挂载对应的归档文件名，例如 web-var_lib_db.tar
+func dataArchiveName(cname, destination string) string {
+	return cname + "-" + strings.ReplaceAll(strings.Trim(destination, "/"), "/", "_") + ".tar"
+}
+
+// archiveMount 将容器内的目录写入 filename，同时计算大小和 sha256
+func (d *DockerClient) archiveMount(containerID, filename string, archive *DataArchive) error {
+	reader, _, err := d.cli.CopyFromContainer(context.Background(), containerID, archive.Destination)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), reader)
+	if err != nil {
+		return err
+	}
+	archive.Size = size
+	archive.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return file.Close()
+}
+
+// LoadDataManifest 读取数据目录中的归档清单
+func LoadDataManifest(dir string) (*DataManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, dataManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var manifest DataManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("error parsing data manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// SkipExistingVolumes 去掉目标命名卷已经存在的归档，避免覆盖其中的数据，需要在创建容器之前调用
+func (d *DockerClient) SkipExistingVolumes(manifest *DataManifest) error {
+	var archives []DataArchive
+	for _, archive := range manifest.Archives {
+		if archive.Type == string(mount.TypeVolume) && archive.Source != "" {
+			_, err := d.cli.VolumeInspect(context.Background(), archive.Source)
+			if err == nil {
+				ezap.Warnf("Volume %s already exists, skipping the restore of %s", archive.Source, archive.File)
+				continue
+			}
+			if !errdefs.IsNotFound(err) {
+				return err
+			}
+		}
+		archives = append(archives, archive)
+	}
+	manifest.Archives = archives
+	return nil
+}
+
+// RestoreData 在容器启动之前将清单中属于该容器的归档通过 CopyToContainer 写回挂载点，
+// 写入前校验归档的大小和 sha256
+func (d *DockerClient) RestoreData(containerID, cname string, manifest *DataManifest, dir string) error {
+	for _, archive := range manifest.Archives {
+		if archive.Container != cname {
+			continue
+		}
+		filename := filepath.Join(dir, archive.File)
+		if err := verifyDataArchive(filename, archive); err != nil {
+			return err
+		}
+
+		file, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		ezap.Infof("Restoring %s to %s:%s", archive.File, cname, archive.Destination)
+		// 归档中的顶层目录为挂载点的名称，解压到挂载点的上级目录
+		err = d.cli.CopyToContainer(context.Background(), containerID, path.Dir(archive.Destination), file, container.CopyToContainerOptions{})
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("error restoring %s to %s:%s: %w", archive.File, cname, archive.Destination, err)
+		}
+	}
+	return nil
+}
+
+// verifyDataArchive 校验归档文件的大小和 sha256 与清单一致
+func verifyDataArchive(filename string, archive DataArchive) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return err
+	}
+	if size != archive.Size || hex.EncodeToString(hash.Sum(nil)) != archive.SHA256 {
+		return fmt.Errorf("%s does not match the data manifest, the archive may be corrupted", archive.File)
+	}
+	return nil
+}
+
+// StartContainer 启动已创建的容器
+func (d *DockerClient) StartContainer(containerID string) error {
+	return d.cli.ContainerStart(context.Background(), containerID, container.StartOptions{})
+}
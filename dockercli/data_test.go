@@ -0,0 +1,70 @@
+package dockercli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDataArchiveName(t *testing.T) {
+	tests := []struct {
+		cname, destination, want string
+	}{
+		{"db", "/var/lib/db", "db-var_lib_db.tar"},
+		{"web", "/data/", "web-data.tar"},
+	}
+	for _, tt := range tests {
+		if got := dataArchiveName(tt.cname, tt.destination); got != tt.want {
+			t.Errorf("dataArchiveName(%q, %q) = %q, want %q", tt.cname, tt.destination, got, tt.want)
+		}
+	}
+}
+
+func TestDataManifest(t *testing.T) {
+	dir := t.TempDir()
+	content := "archive content"
+	sum := sha256.Sum256([]byte(content))
+	archive := DataArchive{
+		Container:   "db",
+		Type:        "volume",
+		Source:      "dbdata",
+		Destination: "/var/lib/db",
+		File:        "db-var_lib_db.tar",
+		Size:        int64(len(content)),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+	filename := writeTestFile(t, dir, archive.File, content)
+	data, err := json.Marshal(DataManifest{Created: "2024-01-01T00:00:00Z", Archives: []DataArchive{archive}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dir, dataManifestFile, string(data))
+
+	manifest, err := LoadDataManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(manifest.Archives, []DataArchive{archive}) {
+		t.Errorf("archives = %+v, want %+v", manifest.Archives, archive)
+	}
+	if err := verifyDataArchive(filename, archive); err != nil {
+		t.Errorf("verify: %v", err)
+	}
+
+	// 大小相同但内容被修改的归档无法通过校验
+	writeTestFile(t, dir, archive.File, strings.ToUpper(content))
+	if err := verifyDataArchive(filename, archive); err == nil {
+		t.Error("expected an error for a modified archive")
+	}
+	if err := verifyDataArchive(filename+".missing", archive); err == nil {
+		t.Error("expected an error for a missing archive")
+	}
+
+	writeTestFile(t, dir, dataManifestFile, "{")
+	if _, err := LoadDataManifest(dir); err == nil {
+		t.Error("expected an error for an invalid manifest")
+	}
+}
@@ -0,0 +1,89 @@
+package dockercli
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/docker/docker/api/types"
+)
+
+// envFileFormat 判断输出格式是否支持将环境变量写入单独的文件
+func envFileFormat(format string) bool {
+	switch format {
+	case "", "command", "cmd", "shell", "sh", "compose", "yaml", "yml":
+		return true
+	}
+	return false
+}
+
+// envFileName 返回容器环境变量文件的名称
+func envFileName(cname string) string {
+	return cname + ".env"
+}
+
+// inlineEnv 判断环境变量是否需要在命令或 compose 中单独指定。
+// docker 按行读取 --env-file，不处理引号和转义，包含换行的值无法写入文件，也不接受无效的 UTF-8；
+// 脱敏后的占位符需要由 shell 或 compose 展开，也不能写入文件
+func inlineEnv(env string) bool {
+	name, value, _ := strings.Cut(env, "=")
+	return strings.ContainsAny(value, "\r\n") || !utf8.ValidString(env) || secretMarkerRegexp.MatchString(value) ||
+		name == "" || strings.HasPrefix(name, "#") || strings.ContainsAny(name, " \t")
+}
+
+// dotEnvSafe 判断值按照 compose 默认的 .env 规则读取时是否与 --env-file 的结果相同。
+// .env 中未加引号的值会去掉首尾空白和 # 注释，并替换变量，以引号开头的值会去掉引号
+func dotEnvSafe(env string) bool {
+	_, value, _ := strings.Cut(env, "=")
+	return value == strings.TrimSpace(value) && !strings.ContainsAny(value, "$\\") && !strings.Contains(value, " #") &&
+		!strings.HasPrefix(value, "#") && !strings.HasPrefix(value, "'") && !strings.HasPrefix(value, `"`)
+}
+
+// dotEnvFile 判断环境变量文件的每一行按照 .env 的规则读取时是否都与 --env-file 的结果相同
+func dotEnvFile(content string) bool {
+	for _, env := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		if !dotEnvSafe(env) {
+			return false
+		}
+	}
+	return true
+}
+
+// extractEnvFiles 将容器的环境变量写入 <name>.env，返回只包含需要单独指定的环境变量的容器副本、
+// 容器名称到文件名的映射和需要写入输出目录的文件，默认的 PATH 不写入。
+// dotEnv 为 true 时文件可能按照 .env 的规则读取，读取结果不同的值也单独指定
+func extractEnvFiles(containersJSON []types.ContainerJSON, dotEnv bool) ([]types.ContainerJSON, map[string]string, Files) {
+	names := make(map[string]string)
+	files := make(Files)
+	extracted := make([]types.ContainerJSON, 0, len(containersJSON))
+	for _, containerJSON := range containersJSON {
+		if containerJSON.Config == nil {
+			extracted = append(extracted, containerJSON)
+			continue
+		}
+
+		var file strings.Builder
+		var inline []string
+		for _, env := range containerJSON.Config.Env {
+			switch {
+			case env == "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin":
+			case inlineEnv(env) || dotEnv && !dotEnvSafe(env):
+				inline = append(inline, env)
+			default:
+				file.WriteString(env + "\n")
+			}
+		}
+		if file.Len() == 0 {
+			extracted = append(extracted, containerJSON)
+			continue
+		}
+
+		cname := strings.TrimPrefix(containerJSON.Name, "/")
+		names[cname] = envFileName(cname)
+		files[envFileName(cname)] = file.String()
+		config := *containerJSON.Config
+		config.Env = inline
+		containerJSON.Config = &config
+		extracted = append(extracted, containerJSON)
+	}
+	return extracted, names, files
+}
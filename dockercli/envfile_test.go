@@ -0,0 +1,175 @@
+package dockercli
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+// envFileValues 各种需要原样保留的环境变量，包括需要单独指定的多行值
+var envFileValues = []string{
+	"SPACES=hello world",
+	"QUOTES=it's \"quoted\"",
+	"DOLLAR=$HOME ${PATH} $$",
+	"BACKSLASH=C:\\path\\n",
+	"EQUALS=a=b=c",
+	"HASH=value # not a comment",
+	"PADDED=  leading and trailing  ",
+	"UNICODE=中文 ✓",
+	"EMPTY=",
+	"MULTILINE=line1\nline2",
+}
+
+func TestExtractEnvFiles(t *testing.T) {
+	web := testContainer("aaaaaaaaaaaa1111", "web", "A=1", "MULTI=a\nb", "SECRET=__REDACTED_SECRET__", "INVALID=\xff")
+	db := testContainer("bbbbbbbbbbbb2222", "db")
+
+	extracted, names, files := extractEnvFiles([]types.ContainerJSON{web, db}, false)
+	if want := map[string]string{"web": "web.env"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %q, want %q", names, want)
+	}
+	if want := (Files{"web.env": "A=1\n"}); !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %q, want %q", files, want)
+	}
+	if want := []string{"MULTI=a\nb", "SECRET=__REDACTED_SECRET__", "INVALID=\xff"}; !reflect.DeepEqual(extracted[0].Config.Env, want) {
+		t.Errorf("inline env = %q, want %q", extracted[0].Config.Env, want)
+	}
+	// 没有需要写入文件的环境变量时保持不变
+	if !reflect.DeepEqual(extracted[1].Config.Env, db.Config.Env) {
+		t.Errorf("db env = %q", extracted[1].Config.Env)
+	}
+	if len(web.Config.Env) != 5 {
+		t.Errorf("original container was modified: %q", web.Config.Env)
+	}
+}
+
+func TestEnvFileRoundTrip(t *testing.T) {
+	// 只包含按照 .env 规则读取时不变的值
+	dotEnvValues := []string{"SPACES=hello world", "EQUALS=a=b=c", "UNICODE=中文 ✓", "EMPTY=", "MULTILINE=line1\nline2"}
+	tests := []struct {
+		format string
+		swarm  bool
+		values []string
+		ref    string
+	}{
+		{"command", false, envFileValues, `--env-file "$(dirname "$0")"/web.env`},
+		{"compose", false, envFileValues, "env_file:\n      - path: ./web.env\n        format: raw\n"},
+		{"compose", false, dotEnvValues, "env_file:\n      - ./web.env\n"},
+		{"compose", true, envFileValues, "env_file:\n      - ./web.env\n"},
+	}
+	for _, tt := range tests {
+		web := testContainer("aaaaaaaaaaaa1111", "web", tt.values...)
+		opts := &ExportOptions{Format: tt.format, EnvFiles: true, SingleFile: true, Swarm: tt.swarm}
+		out := exportString(t, []types.ContainerJSON{web}, opts)
+		if !strings.Contains(out, tt.ref) {
+			t.Errorf("%s (swarm=%v): output does not reference the env file with %q:\n%s", tt.format, tt.swarm, tt.ref, out)
+		}
+		// swarm 时 .env 规则会改变的值也单独指定
+		for name, want := range map[string]bool{"SPACES": true, "MULTILINE": false, "DOLLAR": !tt.swarm, "PADDED": !tt.swarm} {
+			if !containsEnv(tt.values, name) {
+				continue
+			}
+			if inFile := strings.Contains(opts.ExtraFiles["web.env"], name+"="); inFile != want {
+				t.Errorf("%s (swarm=%v): %s in env file = %v, want %v", tt.format, tt.swarm, name, inFile, want)
+			}
+		}
+
+		dir := t.TempDir()
+		writeTestFile(t, dir, "web.env", opts.ExtraFiles["web.env"])
+		var specs []ContainerSpec
+		var err error
+		if tt.format == "command" {
+			specs, err = LoadCommands(writeTestFile(t, dir, "docker_dump.sh", out))
+		} else {
+			specs, err = LoadCompose(writeTestFile(t, dir, "docker-compose.yml", out))
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		got := envOf(specs[0])
+		want := append([]string{}, tt.values...)
+		sort.Strings(got)
+		sort.Strings(want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s (swarm=%v): env = %q, want %q", tt.format, tt.swarm, got, want)
+		}
+	}
+}
+
+// containsEnv 判断环境变量列表中是否包含 name
+func containsEnv(env []string, name string) bool {
+	for _, e := range env {
+		if strings.HasPrefix(e, name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestEnvFileScriptDir(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+	// 在其他目录中执行脚本时 --env-file 仍然指向脚本所在目录中的文件
+	dir := t.TempDir()
+	script := writeTestFile(t, dir, "print.sh", "printf '%s' "+shellQuote(scriptDirMarker+"/web.env")+"\n")
+	cmd := exec.Command(sh, script)
+	cmd.Dir = os.TempDir()
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "web.env"); string(out) != want {
+		t.Errorf("env file path = %q, want %q", out, want)
+	}
+}
+
+func TestComposeEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XX", "val")
+	writeTestFile(t, dir, "dot.env", "# comment\nexport A=1\nB='literal $XX'\nC=\"q\\n $XX \\\"x\\\" \\$y\"\nD=plain $XX # comment\n\nOVERRIDDEN=file\n")
+	writeTestFile(t, dir, "raw.env", "E='kept' $XX # kept\n")
+	compose := `services:
+  web:
+    image: nginx
+    env_file:
+      - dot.env
+      - path: raw.env
+        format: raw
+      - path: missing.env
+        required: false
+    environment:
+      - OVERRIDDEN=environment
+  single:
+    image: nginx
+    env_file: raw.env
+`
+	specs, err := LoadCompose(writeTestFile(t, dir, "docker-compose.yml", compose))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string][]string)
+	for _, spec := range specs {
+		byName[spec.Name] = spec.Config.Env
+	}
+	wantWeb := []string{"A=1", "B=literal $XX", "C=q\n val \"x\" $y", "D=plain val", "OVERRIDDEN=environment", "E='kept' $XX # kept"}
+	if !reflect.DeepEqual(byName["web"], wantWeb) {
+		t.Errorf("web env = %q, want %q", byName["web"], wantWeb)
+	}
+	// 单个文件使用默认的 .env 规则
+	if want := []string{"E='kept' val"}; !reflect.DeepEqual(byName["single"], want) {
+		t.Errorf("single env = %q, want %q", byName["single"], want)
+	}
+
+	compose = "services:\n  web:\n    image: nginx\n    env_file: missing.env\n"
+	if _, err := LoadCompose(writeTestFile(t, dir, "docker-compose.yml", compose)); err == nil {
+		t.Error("expected an error for a missing required env file")
+	}
+}
@@ -2,6 +2,9 @@ package dockercli
 
 import (
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
 )
 
 // Export 导出容器 json 格式详细信息
@@ -25,17 +28,114 @@ func (d *DockerClient) ExportContainersJSON(dockerNameORID []string, showAll boo
 
 }
 
+// ExportOptions 导出选项
+type ExportOptions struct {
+	Format          string
+	Pretty          bool
+	SingleFile      bool                         // compose、kubernetes 格式时将所有服务写入同一个文件
+	External        bool                         // command、compose、quadlet、ansible 格式时引用已存在的网络和卷
+	Networks        map[string]network.Inspect   // 容器使用的自定义网络，由 InspectNetworks 获取
+	Volumes         map[string]volume.Volume     // 容器使用的命名卷，由 InspectVolumes 获取
+	Minimal         bool                         // 只导出与镜像配置不同的部分
+	Images          map[string]*container.Config // 镜像 ID 到镜像配置的映射，由 InspectImages 获取
+	Swarm           bool                         // compose 格式时生成用于 docker stack deploy 的单个文件
+	TerraformImport bool                         // terraform 格式时为已存在的资源生成 import 块
+	ExtraFiles      Files                        // 导出的配置引用的其他文件，如 seccomp 配置，需要写入输出目录
+	Redactor        *Redactor                    // 不为 nil 时将环境变量和标签中的敏感值替换为占位符
+	CgroupnsDefault container.CgroupnsMode       // 守护进程默认的 cgroup 命名空间模式，由 DefaultCgroupnsMode 获取，为空时视为 private
+	EnvFiles        bool                         // command、compose 格式时将环境变量写入 <name>.env 并通过 --env-file、env_file 引用
+	envFiles        map[string]string            // 容器名称到环境变量文件名的映射，由 extractEnvFiles 生成
+	rawEnvFiles     map[string]bool              // 按照 .env 规则读取时结果不同的环境变量文件，compose 中需要指定 format: raw
+}
+
+// Files 需要写入输出目录的文件，键为包含扩展名的文件名
+type Files map[string]string
+
 // 将容器信息格式化成指定格式
-func ParseContainers(containersJSON []types.ContainerJSON, format string, pretty bool) interface{} {
-	switch format {
+func ParseContainers(containersJSON []types.ContainerJSON, opts *ExportOptions) (interface{}, error) {
+	// json 格式用于备份，始终保留完整信息
+	if opts.Minimal && opts.Format != "json" {
+		containersJSON = minimizeContainers(containersJSON, opts.Images)
+	}
+	// 内联的 seccomp 配置写入单独的文件，导出的配置中通过路径引用
+	if opts.Format != "json" {
+		containersJSON, opts.ExtraFiles = extractSeccompProfiles(containersJSON)
+		containersJSON = clearDefaultCgroupns(containersJSON, opts.CgroupnsDefault)
+	}
+	if opts.Redactor != nil {
+		containersJSON = opts.Redactor.RedactContainers(containersJSON)
+	}
+	// 在脱敏之后提取，包含占位符的环境变量保留在命令和 compose 中
+	if opts.EnvFiles && envFileFormat(opts.Format) {
+		var envFiles Files
+		// docker stack deploy 不支持 format: raw，同一个文件用 docker compose 读取时按照 .env 的规则
+		containersJSON, opts.envFiles, envFiles = extractEnvFiles(containersJSON, opts.Swarm)
+		if opts.ExtraFiles == nil {
+			opts.ExtraFiles = make(Files)
+		}
+		opts.rawEnvFiles = make(map[string]bool)
+		for name, content := range envFiles {
+			opts.ExtraFiles[name] = content
+			opts.rawEnvFiles[name] = !dotEnvFile(content)
+		}
+	}
+
+	dump, err := formatContainers(containersJSON, opts)
+	if err != nil || opts.Redactor == nil {
+		return dump, err
+	}
+	return expandSecretMarkers(dump, opts.Format), nil
+}
+
+// formatContainers 按照 opts.Format 生成输出
+func formatContainers(containersJSON []types.ContainerJSON, opts *ExportOptions) (interface{}, error) {
+	switch opts.Format {
 	case "json":
 		// string
-		return Containers2JSON(containersJSON)
+		return Containers2JSON(containersJSON), nil
 	case "compose", "yaml", "yml":
+		if opts.Swarm {
+			stack, err := Containers2ComposeFile(containersJSON, opts)
+			// map[string]string
+			return map[string]string{"docker-stack": stack}, err
+		}
+		if opts.SingleFile {
+			compose, err := Containers2ComposeFile(containersJSON, opts)
+			// map[string]string
+			return map[string]string{"docker-compose": compose}, err
+		}
 		// map[string]string
-		return Containers2Compose(containersJSON)
+		return Containers2Compose(containersJSON, opts)
+	case "kubernetes", "k8s":
+		if opts.SingleFile {
+			manifests, err := Containers2KubernetesFile(containersJSON)
+			// map[string]string
+			return map[string]string{"kubernetes": manifests}, err
+		}
+		// map[string]string
+		return Containers2Kubernetes(containersJSON)
+	case "quadlet":
+		// Files
+		return Containers2Quadlet(containersJSON, opts)
+	case "systemd":
+		// Files
+		return Containers2Systemd(containersJSON, opts.Pretty)
+	case "nomad":
+		// Files
+		return Containers2Nomad(containersJSON)
+	case "ansible":
+		// Files
+		return Containers2Ansible(containersJSON, opts)
+	case "terraform", "tf":
+		// Files
+		return Containers2Terraform(containersJSON, opts)
 	default:
 		// string
-		return Containers2CMD(containersJSON, pretty)
+		return Containers2CMD(containersJSON, opts)
 	}
 }
+
+// Names 返回排序后的文件名
+func (f Files) Names() []string {
+	return sortedKeys(f)
+}
@@ -0,0 +1,263 @@
+package dockercli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+// 容器之间引用的类型
+const (
+	refLink        = "link"
+	refVolumesFrom = "volumes-from"
+	refNetwork     = "network"
+	refIpc         = "ipc"
+	refPid         = "pid"
+)
+
+// containerRef 容器对另一个容器的引用
+type containerRef struct {
+	Kind   string // 引用类型
+	Target string // 被引用容器的名称
+	Option string // link 的别名或 volumes-from 的读写模式
+}
+
+// containerGraph 导出的容器之间的依赖关系
+type containerGraph struct {
+	refs  map[string][]containerRef // 容器名称到其引用的映射
+	order []string                  // 按依赖关系排序后的容器名称，被依赖的在前
+}
+
+// buildContainerGraph 将容器之间通过 ID 或名称的引用解析为容器名称，并按依赖关系排序。
+// 引用了导出范围之外的容器或存在循环依赖时返回错误。
+func buildContainerGraph(containersJSON []types.ContainerJSON) (*containerGraph, error) {
+	graph := &containerGraph{refs: make(map[string][]containerRef)}
+
+	var names []string
+	for _, containerJSON := range containersJSON {
+		names = append(names, strings.TrimPrefix(containerJSON.Name, "/"))
+	}
+
+	for i, containerJSON := range containersJSON {
+		name := names[i]
+		for _, ref := range rawContainerRefs(containerJSON) {
+			target, ok := resolveContainerRef(ref.Target, containersJSON)
+			if !ok {
+				return nil, fmt.Errorf("container %s references container %s (%s) which is not in the exported selection", name, ref.Target, ref.Kind)
+			}
+			ref.Target = target
+			graph.refs[name] = append(graph.refs[name], ref)
+		}
+	}
+
+	order, err := sortByDependencies(names, func(name string) []string {
+		var deps []string
+		for _, ref := range graph.refs[name] {
+			deps = append(deps, ref.Target)
+		}
+		return deps
+	})
+	if err != nil {
+		return nil, err
+	}
+	graph.order = order
+	return graph, nil
+}
+
+// rawContainerRefs 提取容器配置中对其他容器的引用，Target 为原始的 ID 或名称
+func rawContainerRefs(containerJSON types.ContainerJSON) []containerRef {
+	var refs []containerRef
+	hostConfig := containerJSON.HostConfig
+
+	for _, link := range hostConfig.Links {
+		// inspect 中的 link 形如 /db:/web/alias
+		source, alias, _ := strings.Cut(convertLink(link), ":")
+		refs = append(refs, containerRef{Kind: refLink, Target: source, Option: alias})
+	}
+	for _, from := range hostConfig.VolumesFrom {
+		source, mode, _ := strings.Cut(from, ":")
+		refs = append(refs, containerRef{Kind: refVolumesFrom, Target: source, Option: mode})
+	}
+	if target, found := strings.CutPrefix(string(hostConfig.NetworkMode), "container:"); found {
+		refs = append(refs, containerRef{Kind: refNetwork, Target: target})
+	}
+	if target, found := strings.CutPrefix(string(hostConfig.IpcMode), "container:"); found {
+		refs = append(refs, containerRef{Kind: refIpc, Target: target})
+	}
+	if target, found := strings.CutPrefix(string(hostConfig.PidMode), "container:"); found {
+		refs = append(refs, containerRef{Kind: refPid, Target: target})
+	}
+	return refs
+}
+
+// resolveContainerRef 在导出的容器中查找名称、完整 ID 或唯一 ID 前缀匹配的容器，返回容器名称
+func resolveContainerRef(ref string, containersJSON []types.ContainerJSON) (string, bool) {
+	ref = strings.TrimPrefix(ref, "/")
+	var matched []string
+	for _, containerJSON := range containersJSON {
+		name := strings.TrimPrefix(containerJSON.Name, "/")
+		if name == ref || containerJSON.ID == ref {
+			return name, true
+		}
+		if strings.HasPrefix(containerJSON.ID, ref) {
+			matched = append(matched, name)
+		}
+	}
+	if len(matched) == 1 {
+		return matched[0], true
+	}
+	return "", false
+}
+
+// sortByDependencies 按依赖关系对名称做拓扑排序，被依赖的排在前面，其余保持原有顺序。
+// 不在 names 中的依赖会被忽略，存在循环依赖时返回错误。
+func sortByDependencies(names []string, deps func(name string) []string) ([]string, error) {
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		index[name] = i
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(names))
+	var order []string
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			start := 0
+			for i, p := range path {
+				if p == name {
+					start = i
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), name)
+			return fmt.Errorf("circular dependency between containers: %s", strings.Join(cycle, " -> "))
+		}
+		state[name] = visiting
+		path = append(path, name)
+
+		targets := deps(name)
+		sort.SliceStable(targets, func(i, j int) bool { return index[targets[i]] < index[targets[j]] })
+		for _, target := range targets {
+			if _, ok := index[target]; !ok || target == name {
+				continue
+			}
+			if err := visit(target); err != nil {
+				return err
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// dependencies 返回容器依赖的其他容器名称，去重并保持引用顺序
+func (g *containerGraph) dependencies(name string) []string {
+	var deps []string
+	seen := make(map[string]bool)
+	for _, ref := range g.refs[name] {
+		if !seen[ref.Target] {
+			seen[ref.Target] = true
+			deps = append(deps, ref.Target)
+		}
+	}
+	return deps
+}
+
+// ref 返回容器指定类型的第一个引用
+func (g *containerGraph) ref(name, kind string) (containerRef, bool) {
+	for _, ref := range g.refs[name] {
+		if ref.Kind == kind {
+			return ref, true
+		}
+	}
+	return containerRef{}, false
+}
+
+// refsOf 返回容器指定类型的所有引用
+func (g *containerGraph) refsOf(name, kind string) []containerRef {
+	var refs []containerRef
+	for _, ref := range g.refs[name] {
+		if ref.Kind == kind {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// sortContainers 按依赖关系排序容器
+func (g *containerGraph) sortContainers(containersJSON []types.ContainerJSON) []types.ContainerJSON {
+	byName := make(map[string]types.ContainerJSON, len(containersJSON))
+	for _, containerJSON := range containersJSON {
+		byName[strings.TrimPrefix(containerJSON.Name, "/")] = containerJSON
+	}
+	sorted := make([]types.ContainerJSON, 0, len(containersJSON))
+	for _, name := range g.order {
+		sorted = append(sorted, byName[name])
+	}
+	return sorted
+}
+
+// SortSpecs 按照 link、volumes-from 和 container: 网络等引用对待创建的容器排序，
+// 被引用的容器先创建。引用不在列表中的容器视为已存在。
+func SortSpecs(specs []ContainerSpec) ([]ContainerSpec, error) {
+	// 未命名或重名的容器使用带序号的键，保证不会丢失
+	byName := make(map[string]ContainerSpec, len(specs))
+	var names []string
+	for i, spec := range specs {
+		key := spec.Name
+		if _, exists := byName[key]; exists || key == "" {
+			key = fmt.Sprintf("%s#%d", spec.Name, i)
+		}
+		byName[key] = spec
+		names = append(names, key)
+	}
+
+	order, err := sortByDependencies(names, func(name string) []string {
+		hostConfig := byName[name].HostConfig
+		var deps []string
+		for _, link := range hostConfig.Links {
+			source, _, _ := strings.Cut(link, ":")
+			deps = append(deps, strings.TrimPrefix(source, "/"))
+		}
+		for _, from := range hostConfig.VolumesFrom {
+			source, _, _ := strings.Cut(from, ":")
+			deps = append(deps, source)
+		}
+		for _, mode := range []string{string(hostConfig.NetworkMode), string(hostConfig.IpcMode), string(hostConfig.PidMode)} {
+			if target, found := strings.CutPrefix(mode, "container:"); found {
+				deps = append(deps, target)
+			}
+		}
+		return deps
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := make([]ContainerSpec, 0, len(specs))
+	for _, name := range order {
+		sorted = append(sorted, byName[name])
+	}
+	return sorted, nil
+}
@@ -0,0 +1,137 @@
+package dockercli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestBuildContainerGraph(t *testing.T) {
+	web := testContainer("aaaaaaaaaaaa1111", "web")
+	web.HostConfig.Links = []string{"/db:/web/database"}
+	web.HostConfig.VolumesFrom = []string{"cccccccccccc3333:ro"}
+	sidecar := testContainer("bbbbbbbbbbbb2222", "sidecar")
+	sidecar.HostConfig.NetworkMode = "container:aaaaaaaaaaaa1111"
+	sidecar.HostConfig.PidMode = "container:aaaa"
+	db := testContainer("dddddddddddd4444", "db")
+	data := testContainer("cccccccccccc3333", "data")
+
+	graph, err := buildContainerGraph([]types.ContainerJSON{sidecar, web, db, data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"db", "data", "web", "sidecar"}; !reflect.DeepEqual(graph.order, want) {
+		t.Errorf("order = %q, want %q", graph.order, want)
+	}
+	wantRefs := []containerRef{
+		{Kind: refLink, Target: "db", Option: "database"},
+		{Kind: refVolumesFrom, Target: "data", Option: "ro"},
+	}
+	if !reflect.DeepEqual(graph.refs["web"], wantRefs) {
+		t.Errorf("web refs = %+v, want %+v", graph.refs["web"], wantRefs)
+	}
+	if want := []string{"web"}; !reflect.DeepEqual(graph.dependencies("sidecar"), want) {
+		t.Errorf("sidecar dependencies = %q, want %q", graph.dependencies("sidecar"), want)
+	}
+}
+
+func TestBuildContainerGraphErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(a, b *types.ContainerJSON)
+		err   string
+	}{
+		{
+			name:  "link outside the selection",
+			setup: func(a, b *types.ContainerJSON) { a.HostConfig.Links = []string{"/cache:/a/cache"} },
+			err:   "container a references container cache (link) which is not in the exported selection",
+		},
+		{
+			name:  "network container outside the selection",
+			setup: func(a, b *types.ContainerJSON) { b.HostConfig.NetworkMode = "container:ffffffffffff" },
+			err:   "container b references container ffffffffffff (network) which is not in the exported selection",
+		},
+		{
+			name: "ambiguous id prefix",
+			setup: func(a, b *types.ContainerJSON) {
+				a.ID, b.ID = "abcdef000001", "abcdef000002"
+				a.HostConfig.IpcMode = "container:abcdef"
+			},
+			err: "container a references container abcdef (ipc) which is not in the exported selection",
+		},
+		{
+			name: "cycle",
+			setup: func(a, b *types.ContainerJSON) {
+				a.HostConfig.VolumesFrom = []string{"b"}
+				b.HostConfig.Links = []string{"/a:/b/a"}
+			},
+			err: "circular dependency between containers: a -> b -> a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := testContainer("aaaaaaaaaaaa1111", "a")
+			b := testContainer("bbbbbbbbbbbb2222", "b")
+			tt.setup(&a, &b)
+			containers := []types.ContainerJSON{a, b}
+			if _, err := buildContainerGraph(containers); err == nil || err.Error() != tt.err {
+				t.Errorf("buildContainerGraph error = %v, want %q", err, tt.err)
+			}
+			// 各种导出格式都返回同样的错误
+			for _, format := range []string{"command", "compose"} {
+				if _, err := ParseContainers(containers, &ExportOptions{Format: format}); err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("export %s error = %v, want %q", format, err, tt.err)
+				}
+			}
+		})
+	}
+}
+
+func TestContainers2CMDOrder(t *testing.T) {
+	web := testContainer("aaaaaaaaaaaa1111", "web")
+	web.HostConfig.Links = []string{"/db:/web/database"}
+	db := testContainer("bbbbbbbbbbbb2222", "db")
+
+	out := exportString(t, []types.ContainerJSON{web, db}, &ExportOptions{Format: "command"})
+	if strings.Index(out, "--name db") > strings.Index(out, "--name web") {
+		t.Errorf("db should be created before web:\n%s", out)
+	}
+	if !strings.Contains(out, "--link db:database") {
+		t.Errorf("link is not resolved to the container name:\n%s", out)
+	}
+}
+
+func TestSortSpecs(t *testing.T) {
+	spec := func(name string, hostConfig container.HostConfig) ContainerSpec {
+		return ContainerSpec{Name: name, Config: &container.Config{}, HostConfig: &hostConfig}
+	}
+	specs := []ContainerSpec{
+		spec("web", container.HostConfig{Links: []string{"db:database"}, VolumesFrom: []string{"data:ro"}}),
+		spec("sidecar", container.HostConfig{NetworkMode: "container:web"}),
+		spec("db", container.HostConfig{}),
+		// 引用不在列表中的容器视为已存在
+		spec("data", container.HostConfig{IpcMode: "container:existing"}),
+	}
+	sorted, err := SortSpecs(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, spec := range sorted {
+		names = append(names, spec.Name)
+	}
+	if want := []string{"db", "data", "web", "sidecar"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("order = %q, want %q", names, want)
+	}
+
+	specs = []ContainerSpec{
+		spec("a", container.HostConfig{PidMode: "container:b"}),
+		spec("b", container.HostConfig{NetworkMode: "container:a"}),
+	}
+	if _, err := SortSpecs(specs); err == nil || !strings.Contains(err.Error(), "circular dependency") {
+		t.Errorf("SortSpecs error = %v, want a circular dependency error", err)
+	}
+}
@@ -0,0 +1,81 @@
+package dockercli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+)
+
+// testContainer 返回与 docker inspect 结果结构一致的最小容器
+func testContainer(id, name string, env ...string) types.ContainerJSON {
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:         id,
+			Name:       "/" + name,
+			Created:    "2024-01-01T00:00:00Z",
+			Image:      "sha256:0123456789ab",
+			HostConfig: &container.HostConfig{NetworkMode: "default"},
+		},
+		Config: &container.Config{
+			Hostname: id[:12],
+			Image:    "nginx:latest",
+			Env:      append([]string{"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"}, env...),
+		},
+		NetworkSettings: &types.NetworkSettings{
+			Networks: map[string]*network.EndpointSettings{"bridge": {}},
+		},
+	}
+}
+
+// exportString 导出容器并将所有输出文件按名称顺序拼接为一个字符串
+func exportString(t *testing.T, containersJSON []types.ContainerJSON, opts *ExportOptions) string {
+	t.Helper()
+	dump, err := ParseContainers(containersJSON, opts)
+	if err != nil {
+		t.Fatalf("export %s: %v", opts.Format, err)
+	}
+	switch dump := dump.(type) {
+	case string:
+		return dump
+	case map[string]string:
+		var out strings.Builder
+		for _, name := range sortedKeys(dump) {
+			out.WriteString(dump[name])
+		}
+		return out.String()
+	case Files:
+		var out strings.Builder
+		for _, name := range dump.Names() {
+			out.WriteString(dump[name])
+		}
+		return out.String()
+	}
+	t.Fatalf("export %s: unexpected output %T", opts.Format, dump)
+	return ""
+}
+
+// writeTestFile 在 dir 中写入文件并返回文件路径
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	filename := filepath.Join(dir, name)
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// envOf 返回导入结果中除 PATH 之外的环境变量
+func envOf(spec ContainerSpec) []string {
+	var env []string
+	for _, e := range spec.Config.Env {
+		if !strings.HasPrefix(e, "PATH=") {
+			env = append(env, e)
+		}
+	}
+	return env
+}
@@ -0,0 +1,228 @@
+package dockercli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+	"github.com/fimreal/goutils/ezap"
+)
+
+// ContainerSpec 描述重新创建一个容器所需的参数
+type ContainerSpec struct {
+	Name             string
+	Config           *container.Config
+	HostConfig       *container.HostConfig
+	NetworkingConfig *network.NetworkingConfig
+}
+
+// LoadContainersJSON 解析 Containers2JSON 导出的 JSON 文件
+func LoadContainersJSON(data []byte) ([]ContainerSpec, error) {
+	var containersJSON []types.ContainerJSON
+	if err := json.Unmarshal(data, &containersJSON); err != nil {
+		return nil, fmt.Errorf("error parsing containers JSON: %w", err)
+	}
+
+	// 旧容器 ID 到名称的映射，用于改写 container:<id> 之类的引用
+	names := make(map[string]string)
+	for _, containerJSON := range containersJSON {
+		names[containerJSON.ID] = strings.TrimPrefix(containerJSON.Name, "/")
+	}
+
+	var specs []ContainerSpec
+	for i, containerJSON := range containersJSON {
+		if containerJSON.ContainerJSONBase == nil || containerJSON.Config == nil || containerJSON.HostConfig == nil {
+			return nil, fmt.Errorf("container #%d: missing Config or HostConfig", i)
+		}
+		specs = append(specs, ContainerJSON2Spec(containerJSON, names))
+	}
+	return specs, nil
+}
+
+// ContainerJSON2Spec 将 inspect 结果还原为创建容器的参数
+// names 为旧容器 ID 到名称的映射，可以为 nil
+func ContainerJSON2Spec(containerJSON types.ContainerJSON, names map[string]string) ContainerSpec {
+	config := *containerJSON.Config
+	hostConfig := *containerJSON.HostConfig
+
+	// 未指定 hostname 时 docker 使用容器短 ID，重新创建时不应沿用旧 ID
+	config.Hostname = customHostname(containerJSON)
+
+	// inspect 中的 link 形如 /db:/web/alias，创建时需要 db:alias
+	var links []string
+	for _, link := range hostConfig.Links {
+		links = append(links, convertLink(link))
+	}
+	hostConfig.Links = links
+
+	hostConfig.NetworkMode = container.NetworkMode(renameContainerRef(string(hostConfig.NetworkMode), names))
+	hostConfig.IpcMode = container.IpcMode(renameContainerRef(string(hostConfig.IpcMode), names))
+	hostConfig.PidMode = container.PidMode(renameContainerRef(string(hostConfig.PidMode), names))
+	var volumesFrom []string
+	for _, from := range hostConfig.VolumesFrom {
+		source, mode, _ := strings.Cut(from, ":")
+		if name, ok := names[source]; ok {
+			source = name
+		}
+		if mode != "" {
+			source += ":" + mode
+		}
+		volumesFrom = append(volumesFrom, source)
+	}
+	hostConfig.VolumesFrom = volumesFrom
+
+	// 只保留用户可以指定的网络参数，运行时分配的 ID、IP 等信息丢弃
+	endpoints := make(map[string]*network.EndpointSettings)
+	if containerJSON.NetworkSettings != nil {
+		for name, settings := range containerJSON.NetworkSettings.Networks {
+			if settings == nil {
+				continue
+			}
+			endpoints[name] = userEndpointSettings(settings, containerJSON.ID)
+		}
+	}
+
+	return ContainerSpec{
+		Name:             strings.TrimPrefix(containerJSON.Name, "/"),
+		Config:           &config,
+		HostConfig:       &hostConfig,
+		NetworkingConfig: &network.NetworkingConfig{EndpointsConfig: endpoints},
+	}
+}
+
+// convertLink 将 /db:/web/alias 转换为 db:alias
+func convertLink(link string) string {
+	source, target, found := strings.Cut(link, ":")
+	source = strings.TrimPrefix(source, "/")
+	if !found {
+		return source
+	}
+	alias := target[strings.LastIndex(target, "/")+1:]
+	if alias == source {
+		return source
+	}
+	return source + ":" + alias
+}
+
+// renameContainerRef 将 container:<id> 中的旧容器 ID 替换为容器名称
+func renameContainerRef(mode string, names map[string]string) string {
+	ref, found := strings.CutPrefix(mode, "container:")
+	if !found {
+		return mode
+	}
+	if name, ok := names[ref]; ok {
+		return "container:" + name
+	}
+	return mode
+}
+
+// userEndpointSettings 复制网络端点中用户可配置的部分，docker 根据 IP 生成的 MAC 地址不复制
+func userEndpointSettings(settings *network.EndpointSettings, containerID string) *network.EndpointSettings {
+	endpoint := &network.EndpointSettings{
+		Links:      settings.Links,
+		MacAddress: customMacAddress(settings),
+		DriverOpts: settings.DriverOpts,
+	}
+	if settings.IPAMConfig != nil {
+		ipam := *settings.IPAMConfig
+		endpoint.IPAMConfig = &ipam
+	}
+	for _, alias := range settings.Aliases {
+		// 旧版本 docker 会自动把短 ID 加入别名
+		if alias == shortID(containerID) {
+			continue
+		}
+		endpoint.Aliases = append(endpoint.Aliases, alias)
+	}
+	return endpoint
+}
+
+// parseRestartPolicy 解析 no、always、unless-stopped、on-failure[:max-retries] 形式的重启策略
+func parseRestartPolicy(policy string) (container.RestartPolicy, error) {
+	name, retries, found := strings.Cut(policy, ":")
+	restartPolicy := container.RestartPolicy{Name: container.RestartPolicyMode(name)}
+	if found {
+		count, err := strconv.Atoi(retries)
+		if err != nil {
+			return restartPolicy, fmt.Errorf("invalid restart policy %q: %w", policy, err)
+		}
+		restartPolicy.MaximumRetryCount = count
+	}
+	if err := container.ValidateRestartPolicy(restartPolicy); err != nil {
+		return restartPolicy, err
+	}
+	return restartPolicy, nil
+}
+
+// CreateContainer 按照 spec 创建容器，start 为 true 时创建后立即启动，返回新容器 ID
+func (d *DockerClient) CreateContainer(spec ContainerSpec, start bool) (string, error) {
+	ctx := context.Background()
+
+	if err := d.ensureImage(ctx, spec.Config.Image); err != nil {
+		return "", err
+	}
+
+	// 旧版本 API 创建时只能指定一个网络，其余网络在创建后再连接
+	primary := string(spec.HostConfig.NetworkMode)
+	createNetworking := &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{}}
+	var extraNetworks []string
+	if spec.NetworkingConfig != nil {
+		for name, endpoint := range spec.NetworkingConfig.EndpointsConfig {
+			if name == primary || (primary == "default" && name == "bridge") {
+				createNetworking.EndpointsConfig[name] = endpoint
+			} else {
+				extraNetworks = append(extraNetworks, name)
+			}
+		}
+	}
+
+	resp, err := d.cli.ContainerCreate(ctx, spec.Config, spec.HostConfig, createNetworking, nil, spec.Name)
+	if err != nil {
+		return "", fmt.Errorf("error creating container %s: %w", spec.Name, err)
+	}
+	for _, warning := range resp.Warnings {
+		ezap.Warnf("%s: %s", spec.Name, warning)
+	}
+
+	for _, name := range extraNetworks {
+		err := d.cli.NetworkConnect(ctx, name, resp.ID, spec.NetworkingConfig.EndpointsConfig[name])
+		if err != nil {
+			return resp.ID, fmt.Errorf("error connecting container %s to network %s: %w", spec.Name, name, err)
+		}
+	}
+
+	if start {
+		if err := d.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+			return resp.ID, fmt.Errorf("error starting container %s: %w", spec.Name, err)
+		}
+	}
+	return resp.ID, nil
+}
+
+// ensureImage 本地不存在镜像时拉取
+func (d *DockerClient) ensureImage(ctx context.Context, ref string) error {
+	_, _, err := d.cli.ImageInspectWithRaw(ctx, ref)
+	if err == nil {
+		return nil
+	}
+	if !errdefs.IsNotFound(err) {
+		return err
+	}
+
+	ezap.Infof("Pulling image %s", ref)
+	reader, err := d.cli.ImagePull(ctx, ref, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("error pulling image %s: %w", ref, err)
+	}
+	defer reader.Close()
+	_, err = io.Copy(io.Discard, reader)
+	return err
+}
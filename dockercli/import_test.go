@@ -0,0 +1,32 @@
+package dockercli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types/network"
+)
+
+func TestUserEndpointSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings network.EndpointSettings
+		mac      string
+		aliases  []string
+	}{
+		{"generated mac", network.EndpointSettings{IPAddress: "172.18.0.2", MacAddress: "02:42:ac:12:00:02"}, "", nil},
+		{"custom mac", network.EndpointSettings{IPAddress: "172.18.0.2", MacAddress: "92:d0:c6:0a:29:33"}, "92:d0:c6:0a:29:33", nil},
+		{"short id alias", network.EndpointSettings{Aliases: []string{"aaaaaaaaaaaa", "web"}}, "", []string{"web"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := userEndpointSettings(&tt.settings, "aaaaaaaaaaaa1111")
+			if endpoint.MacAddress != tt.mac {
+				t.Errorf("mac = %q, want %q", endpoint.MacAddress, tt.mac)
+			}
+			if !reflect.DeepEqual(endpoint.Aliases, tt.aliases) {
+				t.Errorf("aliases = %q, want %q", endpoint.Aliases, tt.aliases)
+			}
+		})
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
 )
 
 // Inspect 查看容器详细信息
@@ -22,6 +25,65 @@ func (d *DockerClient) Inspect(containers []types.Container) (containersJSON []t
 	return
 }
 
+// InspectNetworks 查询容器使用的自定义网络，跳过 bridge、host、none 等内置网络
+func (d *DockerClient) InspectNetworks(containersJSON []types.ContainerJSON) (map[string]network.Inspect, error) {
+	networks := make(map[string]network.Inspect)
+	for _, containerJSON := range containersJSON {
+		for _, name := range userNetworks(containerJSON) {
+			if _, ok := networks[name]; ok {
+				continue
+			}
+			networkJSON, err := d.cli.NetworkInspect(context.Background(), name, network.InspectOptions{})
+			if err != nil {
+				return nil, err
+			}
+			networks[name] = networkJSON
+		}
+	}
+	return networks, nil
+}
+
+// DefaultCgroupnsMode 返回守护进程默认的 cgroup 命名空间模式，cgroup v1 为 host，v2 为 private
+func (d *DockerClient) DefaultCgroupnsMode() (container.CgroupnsMode, error) {
+	info, err := d.cli.Info(context.Background())
+	if err != nil {
+		return "", err
+	}
+	if info.CgroupVersion == "1" {
+		return container.CgroupnsModeHost, nil
+	}
+	return container.CgroupnsModePrivate, nil
+}
+
+// InspectVolumes 查询容器挂载的命名卷，获取驱动选项和标签，跳过匿名卷
+func (d *DockerClient) InspectVolumes(containersJSON []types.ContainerJSON) (map[string]volume.Volume, error) {
+	volumes := make(map[string]volume.Volume)
+	for _, mount := range referencedVolumes(containersJSON) {
+		volumeJSON, err := d.cli.VolumeInspect(context.Background(), mount.Name)
+		if err != nil {
+			return nil, err
+		}
+		volumes[mount.Name] = volumeJSON
+	}
+	return volumes, nil
+}
+
+// InspectImages 查询容器使用的镜像，返回镜像 ID 到镜像配置的映射
+func (d *DockerClient) InspectImages(containersJSON []types.ContainerJSON) (map[string]*container.Config, error) {
+	images := make(map[string]*container.Config)
+	for _, containerJSON := range containersJSON {
+		if _, ok := images[containerJSON.Image]; ok {
+			continue
+		}
+		imageJSON, err := d.InspectImageByID(containerJSON.Image)
+		if err != nil {
+			return nil, err
+		}
+		images[containerJSON.Image] = imageJSON.Config
+	}
+	return images, nil
+}
+
 func (d *DockerClient) InspectImageByID(imageID string) (imageJSON types.ImageInspect, err error) {
 	imageJSON, _, err = d.cli.ImageInspectWithRaw(context.Background(), imageID)
 	return
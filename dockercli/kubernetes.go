@@ -0,0 +1,475 @@
+package dockercli
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
+	"github.com/fimreal/goutils/ezap"
+)
+
+// k8sInvalidNameRegexp kubernetes 资源名称（RFC 1123 label）中不允许的字符
+var k8sInvalidNameRegexp = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// k8sName 将容器、卷名称转换为合法的 kubernetes 资源名称
+func k8sName(name string) string {
+	name = k8sInvalidNameRegexp.ReplaceAllString(strings.ToLower(name), "-")
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	name = strings.Trim(name, "-")
+	if name == "" {
+		return "container"
+	}
+	return name
+}
+
+// k8sVolume pod 中的一个卷及其在容器中的挂载点
+type k8sVolume struct {
+	Name      string
+	Source    string // 卷的定义，例如 hostPath 或 persistentVolumeClaim 部分
+	MountPath string
+	ReadOnly  bool
+	Claim     string // 需要创建的 PersistentVolumeClaim 名称
+}
+
+// Containers2Kubernetes 将每个容器转换为 Deployment，发布了端口的容器额外生成 Service，
+// 命名卷转换为 PersistentVolumeClaim，绑定挂载转换为 hostPath。返回容器名称到清单内容的映射
+func Containers2Kubernetes(containersJSON []types.ContainerJSON) (map[string]string, error) {
+	manifests := make(map[string]string)
+	for _, containerJSON := range containersJSON {
+		cname := strings.TrimPrefix(containerJSON.Name, "/")
+		manifests[cname] = strings.Join(generateKubernetesManifests(containerJSON, nil), "---\n")
+	}
+	return manifests, nil
+}
+
+// Containers2KubernetesFile 将所有容器的清单写入同一个文件，多个容器共用的 PersistentVolumeClaim 只生成一次
+func Containers2KubernetesFile(containersJSON []types.ContainerJSON) (string, error) {
+	var documents []string
+	claims := make(map[string]bool)
+	for _, containerJSON := range containersJSON {
+		documents = append(documents, generateKubernetesManifests(containerJSON, claims)...)
+	}
+	return strings.Join(documents, "---\n"), nil
+}
+
+// generateKubernetesManifests 生成单个容器的 PersistentVolumeClaim、Deployment 和 Service，
+// claims 记录已经生成过的 PersistentVolumeClaim，为 nil 时只在单个容器内去重
+func generateKubernetesManifests(containerJSON types.ContainerJSON, claims map[string]bool) []string {
+	var documents []string
+	cname := strings.TrimPrefix(containerJSON.Name, "/")
+	name := k8sName(cname)
+
+	if claims == nil {
+		claims = make(map[string]bool)
+	}
+	volumes := kubernetesVolumes(containerJSON)
+	for _, volume := range volumes {
+		if volume.Claim == "" || claims[volume.Claim] {
+			continue
+		}
+		claims[volume.Claim] = true
+		documents = append(documents, generatePersistentVolumeClaim(volume.Claim))
+	}
+
+	documents = append(documents, generateDeployment(containerJSON, name, volumes))
+	if service := generateService(containerJSON, name); service != "" {
+		documents = append(documents, service)
+	}
+	return documents
+}
+
+// kubernetesVolumes 将容器的挂载转换为 pod 卷，匿名卷和 tmpfs 使用 emptyDir
+func kubernetesVolumes(containerJSON types.ContainerJSON) []k8sVolume {
+	var volumes []k8sVolume
+	for i, mount := range containerJSON.Mounts {
+		volume := k8sVolume{
+			Name:      fmt.Sprintf("volume-%d", i),
+			MountPath: mount.Destination,
+			ReadOnly:  !mount.RW,
+		}
+		switch {
+		case mount.Type == "bind":
+			volume.Source = fmt.Sprintf("hostPath:\n            path: %s", yamlScalar(mount.Source))
+		case mount.Type == "volume" && mount.Name != "" && !isAnonymousVolume(mount.Name):
+			volume.Claim = k8sName(mount.Name)
+			volume.Name = volume.Claim
+			volume.Source = fmt.Sprintf("persistentVolumeClaim:\n            claimName: %s", volume.Claim)
+		case mount.Type == "tmpfs":
+			volume.Source = "emptyDir:\n            medium: Memory"
+		default:
+			volume.Source = "emptyDir: {}"
+		}
+		volumes = append(volumes, volume)
+	}
+
+	// --tmpfs 指定的挂载不出现在 Mounts 中
+	destinations := make([]string, 0, len(containerJSON.HostConfig.Tmpfs))
+	for destination := range containerJSON.HostConfig.Tmpfs {
+		destinations = append(destinations, destination)
+	}
+	sort.Strings(destinations)
+	for _, destination := range destinations {
+		volumes = append(volumes, k8sVolume{
+			Name:      fmt.Sprintf("tmpfs-%d", len(volumes)),
+			Source:    "emptyDir:\n            medium: Memory",
+			MountPath: destination,
+		})
+	}
+	return volumes
+}
+
+// generatePersistentVolumeClaim 生成命名卷对应的 PersistentVolumeClaim，容量需要按实际情况调整
+func generatePersistentVolumeClaim(name string) string {
+	var claim strings.Builder
+	claim.WriteString("apiVersion: v1\n")
+	claim.WriteString("kind: PersistentVolumeClaim\n")
+	claim.WriteString("metadata:\n")
+	claim.WriteString(fmt.Sprintf("  name: %s\n", name))
+	claim.WriteString("spec:\n")
+	claim.WriteString("  accessModes:\n")
+	claim.WriteString("    - ReadWriteOnce\n")
+	claim.WriteString("  resources:\n")
+	claim.WriteString("    requests:\n")
+	claim.WriteString("      storage: 1Gi # TODO: adjust to the size of the docker volume\n")
+	return claim.String()
+}
+
+// generateDeployment 生成单副本的 Deployment
+func generateDeployment(containerJSON types.ContainerJSON, name string, volumes []k8sVolume) string {
+	var deployment strings.Builder
+	cname := strings.TrimPrefix(containerJSON.Name, "/")
+	config := containerJSON.Config
+	hostConfig := containerJSON.HostConfig
+
+	if target, found := strings.CutPrefix(string(hostConfig.NetworkMode), "container:"); found {
+		ezap.Warnf("%s: shares the network namespace of container %s, consider running both in the same pod", cname, target)
+	}
+	if hostConfig.RestartPolicy.Name != "" && hostConfig.RestartPolicy.Name != "always" && hostConfig.RestartPolicy.Name != "unless-stopped" {
+		ezap.Warnf("%s: restart policy %q is not supported by Deployments, pods are always restarted", cname, hostConfig.RestartPolicy.Name)
+	}
+
+	deployment.WriteString("apiVersion: apps/v1\n")
+	deployment.WriteString("kind: Deployment\n")
+	deployment.WriteString("metadata:\n")
+	deployment.WriteString(fmt.Sprintf("  name: %s\n", name))
+	deployment.WriteString("  labels:\n")
+	deployment.WriteString(fmt.Sprintf("    app: %s\n", name))
+	deployment.WriteString("spec:\n")
+	deployment.WriteString("  replicas: 1\n")
+	for _, volume := range volumes {
+		// ReadWriteOnce 的卷不能被新旧两个 pod 同时挂载
+		if volume.Claim != "" {
+			deployment.WriteString("  strategy:\n")
+			deployment.WriteString("    type: Recreate\n")
+			break
+		}
+	}
+	deployment.WriteString("  selector:\n")
+	deployment.WriteString("    matchLabels:\n")
+	deployment.WriteString(fmt.Sprintf("      app: %s\n", name))
+	deployment.WriteString("  template:\n")
+	deployment.WriteString("    metadata:\n")
+	deployment.WriteString("      labels:\n")
+	deployment.WriteString(fmt.Sprintf("        app: %s\n", name))
+	deployment.WriteString("    spec:\n")
+
+	// hostname
+	if hostname := customHostname(containerJSON); hostname != "" {
+		deployment.WriteString(fmt.Sprintf("      hostname: %s\n", yamlScalar(hostname)))
+	}
+
+	// host namespaces
+	if hostConfig.NetworkMode.IsHost() {
+		deployment.WriteString("      hostNetwork: true\n")
+	}
+	if hostConfig.PidMode.IsHost() {
+		deployment.WriteString("      hostPID: true\n")
+	}
+	if hostConfig.IpcMode.IsHost() {
+		deployment.WriteString("      hostIPC: true\n")
+	}
+
+	// extra hosts
+	if aliases := hostAliases(hostConfig.ExtraHosts); len(aliases) > 0 {
+		deployment.WriteString("      hostAliases:\n")
+		for _, alias := range aliases {
+			deployment.WriteString(fmt.Sprintf("        - ip: %s\n", yamlQuote(alias.ip)))
+			deployment.WriteString(fmt.Sprintf("          hostnames: %s\n", yamlFlowList(alias.hostnames)))
+		}
+	}
+
+	deployment.WriteString("      containers:\n")
+	deployment.WriteString(fmt.Sprintf("        - name: %s\n", name))
+	deployment.WriteString(fmt.Sprintf("          image: %s\n", yamlScalar(config.Image)))
+
+	// entrypoint 对应 command，cmd 对应 args
+	if len(config.Entrypoint) > 0 {
+		deployment.WriteString(fmt.Sprintf("          command: %s\n", k8sFlowList(config.Entrypoint)))
+	}
+	if len(config.Cmd) > 0 {
+		deployment.WriteString(fmt.Sprintf("          args: %s\n", k8sFlowList(config.Cmd)))
+	}
+
+	// working dir
+	if config.WorkingDir != "" {
+		deployment.WriteString(fmt.Sprintf("          workingDir: %s\n", yamlScalar(config.WorkingDir)))
+	}
+
+	// environment variables
+	var env []string
+	for _, e := range config.Env {
+		if e == "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin" {
+			continue
+		}
+		// 只有变量名的环境变量取自宿主机，kubernetes 中没有对应的值
+		if strings.Contains(e, "=") {
+			env = append(env, e)
+		}
+	}
+	if len(env) > 0 {
+		deployment.WriteString("          env:\n")
+		for _, e := range env {
+			key, value, _ := strings.Cut(e, "=")
+			deployment.WriteString(fmt.Sprintf("            - name: %s\n", yamlScalar(key)))
+			deployment.WriteString(fmt.Sprintf("              value: %s\n", yamlQuote(k8sEscape(value))))
+		}
+	}
+
+	// ports
+	if ports := containerPorts(containerJSON); len(ports) > 0 {
+		deployment.WriteString("          ports:\n")
+		for _, port := range ports {
+			deployment.WriteString(fmt.Sprintf("            - containerPort: %d\n", port.Int()))
+			deployment.WriteString(fmt.Sprintf("              protocol: %s\n", strings.ToUpper(port.Proto())))
+		}
+	}
+
+	// resources
+	if hostConfig.NanoCPUs > 0 || hostConfig.Memory > 0 || hostConfig.MemoryReservation > 0 || hostConfig.CPUShares > 0 {
+		deployment.WriteString("          resources:\n")
+		if hostConfig.NanoCPUs > 0 || hostConfig.Memory > 0 {
+			deployment.WriteString("            limits:\n")
+			if hostConfig.NanoCPUs > 0 {
+				deployment.WriteString(fmt.Sprintf("              cpu: %dm\n", hostConfig.NanoCPUs/1e6))
+			}
+			if hostConfig.Memory > 0 {
+				deployment.WriteString(fmt.Sprintf("              memory: %s\n", k8sQuantity(hostConfig.Memory)))
+			}
+		}
+		// CPU 份额和内存软限制对应调度时的 requests，kubelet 按照 1 个 CPU 等于 1024 份额换算
+		if hostConfig.CPUShares > 0 || hostConfig.MemoryReservation > 0 {
+			deployment.WriteString("            requests:\n")
+			if hostConfig.CPUShares > 0 {
+				deployment.WriteString(fmt.Sprintf("              cpu: %dm\n", max(hostConfig.CPUShares*1000/1024, 1)))
+			}
+			if hostConfig.MemoryReservation > 0 {
+				deployment.WriteString(fmt.Sprintf("              memory: %s\n", k8sQuantity(hostConfig.MemoryReservation)))
+			}
+		}
+	}
+	resources := *hostConfig
+	resources.NanoCPUs, resources.CPUShares, resources.Memory, resources.MemoryReservation = 0, 0, 0, 0
+	for _, option := range resourceOptions(&resources) {
+		ezap.Warnf("%s: %s is not supported by kubernetes, dropped", cname, option[0])
+	}
+
+	// security context
+	var securityContext []string
+	if hostConfig.Privileged {
+		securityContext = append(securityContext, "privileged: true")
+	}
+	if config.User != "" {
+		user, group, _ := strings.Cut(config.User, ":")
+		uid, err := strconv.ParseInt(user, 10, 64)
+		if err == nil {
+			securityContext = append(securityContext, fmt.Sprintf("runAsUser: %d", uid))
+		} else {
+			ezap.Warnf("%s: user %q is not a numeric uid, runAsUser is not set", cname, config.User)
+		}
+		if gid, err := strconv.ParseInt(group, 10, 64); err == nil {
+			securityContext = append(securityContext, fmt.Sprintf("runAsGroup: %d", gid))
+		}
+	}
+	if hostConfig.ReadonlyRootfs {
+		securityContext = append(securityContext, "readOnlyRootFilesystem: true")
+	}
+	if len(hostConfig.CapAdd) > 0 || len(hostConfig.CapDrop) > 0 {
+		securityContext = append(securityContext, "capabilities:")
+		if len(hostConfig.CapAdd) > 0 {
+			securityContext = append(securityContext, "  add: "+yamlFlowList(k8sCapabilities(hostConfig.CapAdd)))
+		}
+		if len(hostConfig.CapDrop) > 0 {
+			securityContext = append(securityContext, "  drop: "+yamlFlowList(k8sCapabilities(hostConfig.CapDrop)))
+		}
+	}
+	if len(securityContext) > 0 {
+		deployment.WriteString("          securityContext:\n")
+		for _, line := range securityContext {
+			deployment.WriteString("            " + line + "\n")
+		}
+	}
+
+	// volume mounts
+	if len(volumes) > 0 {
+		deployment.WriteString("          volumeMounts:\n")
+		for _, volume := range volumes {
+			deployment.WriteString(fmt.Sprintf("            - name: %s\n", volume.Name))
+			deployment.WriteString(fmt.Sprintf("              mountPath: %s\n", yamlScalar(volume.MountPath)))
+			if volume.ReadOnly {
+				deployment.WriteString("              readOnly: true\n")
+			}
+		}
+		deployment.WriteString("      volumes:\n")
+		// 同一个命名卷挂载到多个路径时共用一个 pod 卷
+		defined := make(map[string]bool)
+		for _, volume := range volumes {
+			if defined[volume.Name] {
+				continue
+			}
+			defined[volume.Name] = true
+			deployment.WriteString(fmt.Sprintf("        - name: %s\n", volume.Name))
+			deployment.WriteString(fmt.Sprintf("          %s\n", volume.Source))
+		}
+	}
+
+	return deployment.String()
+}
+
+// generateService 为发布到宿主机的端口生成 Service，Service 端口与宿主机端口一致。没有发布端口时返回空字符串
+func generateService(containerJSON types.ContainerJSON, name string) string {
+	type servicePort struct {
+		port   int
+		target nat.Port
+	}
+	var ports []servicePort
+	added := make(map[string]bool)
+	for _, port := range containerPorts(containerJSON) {
+		for _, binding := range containerJSON.NetworkSettings.Ports[port] {
+			hostPort, err := strconv.Atoi(binding.HostPort)
+			if err != nil {
+				continue
+			}
+			// 同一端口通常同时绑定了 0.0.0.0 和 ::
+			key := binding.HostPort + "/" + port.Proto()
+			if added[key] {
+				continue
+			}
+			added[key] = true
+			ports = append(ports, servicePort{port: hostPort, target: port})
+		}
+	}
+	if len(ports) == 0 {
+		return ""
+	}
+
+	var service strings.Builder
+	service.WriteString("apiVersion: v1\n")
+	service.WriteString("kind: Service\n")
+	service.WriteString("metadata:\n")
+	service.WriteString(fmt.Sprintf("  name: %s\n", name))
+	service.WriteString("spec:\n")
+	service.WriteString("  selector:\n")
+	service.WriteString(fmt.Sprintf("    app: %s\n", name))
+	service.WriteString("  ports:\n")
+	for _, port := range ports {
+		service.WriteString(fmt.Sprintf("    - name: %s-%d\n", port.target.Proto(), port.port))
+		service.WriteString(fmt.Sprintf("      port: %d\n", port.port))
+		service.WriteString(fmt.Sprintf("      targetPort: %d\n", port.target.Int()))
+		service.WriteString(fmt.Sprintf("      protocol: %s\n", strings.ToUpper(port.target.Proto())))
+	}
+	return service.String()
+}
+
+// containerPorts 返回容器暴露和发布的所有端口，按端口号排序
+func containerPorts(containerJSON types.ContainerJSON) []nat.Port {
+	seen := make(map[nat.Port]bool)
+	var ports []nat.Port
+	for port := range containerJSON.Config.ExposedPorts {
+		seen[port] = true
+		ports = append(ports, port)
+	}
+	if containerJSON.NetworkSettings != nil {
+		for port := range containerJSON.NetworkSettings.Ports {
+			if !seen[port] {
+				seen[port] = true
+				ports = append(ports, port)
+			}
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Int() != ports[j].Int() {
+			return ports[i].Int() < ports[j].Int()
+		}
+		return ports[i].Proto() < ports[j].Proto()
+	})
+	return ports
+}
+
+// hostAlias extra_hosts 中同一 IP 对应的主机名
+type hostAlias struct {
+	ip        string
+	hostnames []string
+}
+
+// hostAliases 将 host:ip 形式的 extra hosts 按 IP 合并
+func hostAliases(extraHosts []string) []hostAlias {
+	var aliases []hostAlias
+	index := make(map[string]int)
+	for _, extraHost := range extraHosts {
+		// 新版本 docker 也支持 host=ip
+		host, ip, found := strings.Cut(extraHost, "=")
+		if !found {
+			host, ip, _ = strings.Cut(extraHost, ":")
+		}
+		i, ok := index[ip]
+		if !ok {
+			i = len(aliases)
+			index[ip] = i
+			aliases = append(aliases, hostAlias{ip: ip})
+		}
+		aliases[i].hostnames = append(aliases[i].hostnames, host)
+	}
+	return aliases
+}
+
+// k8sEscape 将 $ 写成 $$，避免 command、args 和 env 中的 $(VAR) 被 kubernetes 展开
+func k8sEscape(s string) string {
+	return strings.ReplaceAll(s, "$", "$$")
+}
+
+// k8sFlowList 生成 command、args 使用的列表
+func k8sFlowList(items []string) string {
+	escaped := make([]string, len(items))
+	for i, item := range items {
+		escaped[i] = k8sEscape(item)
+	}
+	return yamlFlowList(escaped)
+}
+
+// k8sCapabilities 去掉 capability 的 CAP_ 前缀
+func k8sCapabilities(capabilities []string) []string {
+	var trimmed []string
+	for _, capability := range capabilities {
+		trimmed = append(trimmed, strings.TrimPrefix(strings.ToUpper(capability), "CAP_"))
+	}
+	return trimmed
+}
+
+// k8sQuantity 将字节数转换为 kubernetes 的容量表示，能整除时使用 Gi、Mi、Ki
+func k8sQuantity(bytes int64) string {
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"Gi", 1 << 30}, {"Mi", 1 << 20}, {"Ki", 1 << 10}} {
+		if bytes%unit.size == 0 {
+			return fmt.Sprintf("%d%s", bytes/unit.size, unit.suffix)
+		}
+	}
+	return strconv.FormatInt(bytes, 10)
+}
@@ -0,0 +1,57 @@
+package dockercli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/mount"
+	"gopkg.in/yaml.v3"
+)
+
+func TestKubernetesSharedVolume(t *testing.T) {
+	web := testContainer("aaaaaaaaaaaa1111", "web")
+	web.Mounts = []types.MountPoint{
+		{Type: mount.TypeVolume, Name: "data", Destination: "/var/lib/app", RW: true},
+		{Type: mount.TypeVolume, Name: "data", Destination: "/srv/static"},
+	}
+
+	manifests, err := Containers2Kubernetes([]types.ContainerJSON{web})
+	if err != nil {
+		t.Fatal(err)
+	}
+	documents := strings.Split(manifests["web"], "---\n")
+	if got := strings.Count(manifests["web"], "kind: PersistentVolumeClaim"); got != 1 {
+		t.Errorf("got %d PersistentVolumeClaims, want 1", got)
+	}
+
+	var deployment struct {
+		Spec struct {
+			Template struct {
+				Spec struct {
+					Containers []struct {
+						VolumeMounts []struct {
+							Name      string `yaml:"name"`
+							MountPath string `yaml:"mountPath"`
+							ReadOnly  bool   `yaml:"readOnly"`
+						} `yaml:"volumeMounts"`
+					} `yaml:"containers"`
+					Volumes []struct {
+						Name string `yaml:"name"`
+					} `yaml:"volumes"`
+				} `yaml:"spec"`
+			} `yaml:"template"`
+		} `yaml:"spec"`
+	}
+	if err := yaml.Unmarshal([]byte(documents[len(documents)-1]), &deployment); err != nil {
+		t.Fatalf("invalid deployment: %v\n%s", err, documents[len(documents)-1])
+	}
+	pod := deployment.Spec.Template.Spec
+	if len(pod.Volumes) != 1 || pod.Volumes[0].Name != "data" {
+		t.Errorf("pod volumes = %+v, want a single data volume", pod.Volumes)
+	}
+	mounts := pod.Containers[0].VolumeMounts
+	if len(mounts) != 2 || mounts[0].Name != "data" || mounts[1].Name != "data" || mounts[1].MountPath != "/srv/static" || !mounts[1].ReadOnly {
+		t.Errorf("volume mounts = %+v", mounts)
+	}
+}
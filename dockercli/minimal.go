@@ -0,0 +1,110 @@
+package dockercli
+
+import (
+	"reflect"
+	"slices"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/fimreal/goutils/ezap"
+)
+
+// minimizeContainers 去掉容器配置中从镜像继承的部分，只保留 docker run 时指定的参数，
+// images 为镜像 ID 到镜像配置的映射
+func minimizeContainers(containersJSON []types.ContainerJSON, images map[string]*container.Config) []types.ContainerJSON {
+	minimized := make([]types.ContainerJSON, 0, len(containersJSON))
+	for _, containerJSON := range containersJSON {
+		imageConfig, ok := images[containerJSON.Image]
+		if !ok || imageConfig == nil {
+			ezap.Warnf("Image %s of container %s not found, exporting the full configuration", containerJSON.Config.Image, containerJSON.Name)
+			minimized = append(minimized, containerJSON)
+			continue
+		}
+
+		config := minimizeConfig(containerJSON.Config, imageConfig)
+		containerJSON.Config = config
+
+		// 镜像中 VOLUME 声明的匿名卷会在创建容器时自动生成
+		var mounts []types.MountPoint
+		for _, mount := range containerJSON.Mounts {
+			if _, ok := imageConfig.Volumes[mount.Destination]; ok && mount.Type == "volume" && isAnonymousVolume(mount.Name) {
+				continue
+			}
+			mounts = append(mounts, mount)
+		}
+		containerJSON.Mounts = mounts
+
+		minimized = append(minimized, containerJSON)
+	}
+	return minimized
+}
+
+// minimizeConfig 返回去掉与镜像配置相同部分后的容器配置副本
+func minimizeConfig(config, image *container.Config) *container.Config {
+	minimal := *config
+
+	minimal.Env = nil
+	for _, env := range config.Env {
+		if !slices.Contains(image.Env, env) {
+			minimal.Env = append(minimal.Env, env)
+		}
+	}
+
+	minimal.Labels = nil
+	for key, value := range config.Labels {
+		if imageValue, ok := image.Labels[key]; ok && imageValue == value {
+			continue
+		}
+		if minimal.Labels == nil {
+			minimal.Labels = make(map[string]string)
+		}
+		minimal.Labels[key] = value
+	}
+
+	// 指定 --entrypoint 时 docker 会清空镜像的 CMD，此时即使与镜像相同也必须保留 CMD
+	entrypointInherited := slices.Equal(config.Entrypoint, image.Entrypoint)
+	if entrypointInherited {
+		minimal.Entrypoint = nil
+		if slices.Equal(config.Cmd, image.Cmd) {
+			minimal.Cmd = nil
+		}
+	}
+
+	if config.WorkingDir == image.WorkingDir {
+		minimal.WorkingDir = ""
+	}
+	if config.User == image.User {
+		minimal.User = ""
+	}
+	if config.StopSignal == image.StopSignal {
+		minimal.StopSignal = ""
+	}
+	if reflect.DeepEqual(config.Healthcheck, image.Healthcheck) {
+		minimal.Healthcheck = nil
+	}
+
+	minimal.ExposedPorts = nil
+	for port := range config.ExposedPorts {
+		if _, ok := image.ExposedPorts[port]; ok {
+			continue
+		}
+		if minimal.ExposedPorts == nil {
+			minimal.ExposedPorts = make(map[nat.Port]struct{})
+		}
+		minimal.ExposedPorts[port] = struct{}{}
+	}
+
+	minimal.Volumes = nil
+	for volume := range config.Volumes {
+		if _, ok := image.Volumes[volume]; ok {
+			continue
+		}
+		if minimal.Volumes == nil {
+			minimal.Volumes = make(map[string]struct{})
+		}
+		minimal.Volumes[volume] = struct{}{}
+	}
+
+	return &minimal
+}
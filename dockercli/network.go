@@ -0,0 +1,196 @@
+package dockercli
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/fimreal/goutils/ezap"
+)
+
+// referencedNetworks 返回容器用到的自定义网络，按名称排序并去重
+func referencedNetworks(containersJSON []types.ContainerJSON) []string {
+	var networks []string
+	for _, containerJSON := range containersJSON {
+		for _, name := range userNetworks(containerJSON) {
+			if !slices.Contains(networks, name) {
+				networks = append(networks, name)
+			}
+		}
+	}
+	sort.Strings(networks)
+	return networks
+}
+
+// userLabels 去掉 compose 自动为网络和卷添加的标签，这些标签由 compose 管理，重新创建时不需要保留
+func userLabels(labels map[string]string) map[string]string {
+	filtered := make(map[string]string)
+	for key, value := range labels {
+		if !strings.HasPrefix(key, "com.docker.compose.") {
+			filtered[key] = value
+		}
+	}
+	return filtered
+}
+
+// networkCreateArgs 返回重新创建网络的 docker network create 参数，均未加引号
+func networkCreateArgs(networkJSON network.Inspect) []string {
+	var args []string
+	if networkJSON.Driver != "" && networkJSON.Driver != "bridge" {
+		args = append(args, "--driver", networkJSON.Driver)
+	}
+	if networkJSON.IPAM.Driver != "" && networkJSON.IPAM.Driver != "default" {
+		args = append(args, "--ipam-driver", networkJSON.IPAM.Driver)
+	}
+	for _, pool := range networkJSON.IPAM.Config {
+		if pool.Subnet != "" {
+			args = append(args, "--subnet", pool.Subnet)
+		}
+		if pool.IPRange != "" {
+			args = append(args, "--ip-range", pool.IPRange)
+		}
+		if pool.Gateway != "" {
+			args = append(args, "--gateway", pool.Gateway)
+		}
+		for _, host := range sortedKeys(pool.AuxAddress) {
+			args = append(args, "--aux-address", host+"="+pool.AuxAddress[host])
+		}
+	}
+	for _, key := range sortedKeys(networkJSON.IPAM.Options) {
+		args = append(args, "--ipam-opt", key+"="+networkJSON.IPAM.Options[key])
+	}
+	if networkJSON.Internal {
+		args = append(args, "--internal")
+	}
+	if networkJSON.Attachable {
+		args = append(args, "--attachable")
+	}
+	if networkJSON.EnableIPv6 {
+		args = append(args, "--ipv6")
+	}
+	for _, key := range sortedKeys(networkJSON.Options) {
+		args = append(args, "--opt", key+"="+networkJSON.Options[key])
+	}
+	labels := userLabels(networkJSON.Labels)
+	for _, key := range sortedKeys(labels) {
+		args = append(args, "--label", key+"="+labels[key])
+	}
+	return append(args, networkJSON.Name)
+}
+
+// primaryNetwork 返回创建容器时指定的网络名称，默认网络为 bridge，
+// 共享其他容器或使用 host、none 网络时返回空字符串
+func primaryNetwork(containerJSON types.ContainerJSON) string {
+	mode := containerJSON.HostConfig.NetworkMode
+	switch {
+	case mode == "" || mode.IsDefault():
+		return "bridge"
+	case mode.IsContainer() || mode.IsHost() || mode.IsNone():
+		return ""
+	}
+	return string(mode)
+}
+
+// extraNetworks 返回创建容器之后通过 docker network connect 连接的网络
+func extraNetworks(containerJSON types.ContainerJSON) []string {
+	primary := primaryNetwork(containerJSON)
+	if primary == "" || containerJSON.NetworkSettings == nil {
+		return nil
+	}
+	var networks []string
+	for name := range containerJSON.NetworkSettings.Networks {
+		if name != primary && name != "host" && name != "none" {
+			networks = append(networks, name)
+		}
+	}
+	sort.Strings(networks)
+	return networks
+}
+
+// networkEndpoint 返回容器在指定网络中的端点配置，不存在时返回 nil
+func networkEndpoint(containerJSON types.ContainerJSON, name string) *network.EndpointSettings {
+	if containerJSON.NetworkSettings == nil || name == "" {
+		return nil
+	}
+	return containerJSON.NetworkSettings.Networks[name]
+}
+
+// endpointAliases 返回手动指定的网络别名，去掉 docker 自动添加的容器名称和短 ID
+func endpointAliases(containerJSON types.ContainerJSON, endpoint *network.EndpointSettings) []string {
+	cname := strings.TrimPrefix(containerJSON.Name, "/")
+	var aliases []string
+	for _, alias := range endpoint.Aliases {
+		if alias == cname || strings.HasPrefix(containerJSON.ID, alias) || slices.Contains(aliases, alias) {
+			continue
+		}
+		aliases = append(aliases, alias)
+	}
+	return aliases
+}
+
+// customMacAddress 返回手动指定的 MAC 地址。
+// docker 默认根据 IPv4 地址生成 02:42 开头的 MAC 地址，这种情况返回空字符串
+func customMacAddress(endpoint *network.EndpointSettings) string {
+	if endpoint.MacAddress == "" {
+		return ""
+	}
+	if ip := net.ParseIP(endpoint.IPAddress).To4(); ip != nil {
+		generated := fmt.Sprintf("02:42:%02x:%02x:%02x:%02x", ip[0], ip[1], ip[2], ip[3])
+		if strings.EqualFold(endpoint.MacAddress, generated) {
+			return ""
+		}
+	}
+	return endpoint.MacAddress
+}
+
+// endpointOptions 返回端点配置对应的参数，run 为 true 时用于 docker run，否则用于 docker network connect。
+// 默认 bridge 网络中的 link 由 HostConfig.Links 导出，这里只处理自定义网络中的 link
+func endpointOptions(containerJSON types.ContainerJSON, name string, endpoint *network.EndpointSettings, run bool) [][]string {
+	var options [][]string
+	if endpoint == nil {
+		return options
+	}
+
+	aliasFlag := "--alias"
+	if run {
+		aliasFlag = "--network-alias"
+	}
+	for _, alias := range endpointAliases(containerJSON, endpoint) {
+		options = append(options, []string{aliasFlag, alias})
+	}
+	if ipam := endpoint.IPAMConfig; ipam != nil {
+		if ipam.IPv4Address != "" {
+			options = append(options, []string{"--ip", ipam.IPv4Address})
+		}
+		if ipam.IPv6Address != "" {
+			options = append(options, []string{"--ip6", ipam.IPv6Address})
+		}
+	}
+	if mac := customMacAddress(endpoint); mac != "" && run {
+		options = append(options, []string{"--mac-address", mac})
+	}
+	if name != "bridge" {
+		for _, link := range endpoint.Links {
+			options = append(options, []string{"--link", link})
+		}
+	}
+	return options
+}
+
+// networkConnectArgs 返回将容器连接到额外网络的 docker network connect 参数，均未加引号
+func networkConnectArgs(containerJSON types.ContainerJSON, name string) []string {
+	cname := strings.TrimPrefix(containerJSON.Name, "/")
+	endpoint := networkEndpoint(containerJSON, name)
+	if endpoint != nil && customMacAddress(endpoint) != "" {
+		ezap.Warnf("%s: docker network connect does not support a mac address, the mac address on network %s is dropped", cname, name)
+	}
+	var args []string
+	for _, option := range endpointOptions(containerJSON, name, endpoint, false) {
+		args = append(args, option...)
+	}
+	return append(args, name, cname)
+}
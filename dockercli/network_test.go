@@ -0,0 +1,171 @@
+package dockercli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+)
+
+// multiNetworkContainer 返回连接 app 和 backend 两个网络的容器，app 中指定了别名、静态 IP 和 MAC 地址
+func multiNetworkContainer() types.ContainerJSON {
+	web := testContainer("aaaaaaaaaaaa1111", "web")
+	web.HostConfig.NetworkMode = "app"
+	web.NetworkSettings.Networks = map[string]*network.EndpointSettings{
+		"app": {
+			Aliases:    []string{"web", "aaaaaaaaaaaa", "frontend", "frontend"},
+			IPAMConfig: &network.EndpointIPAMConfig{IPv4Address: "172.20.0.10", IPv6Address: "fd00::10"},
+			IPAddress:  "172.20.0.10",
+			MacAddress: "92:d0:c6:0a:29:33",
+		},
+		"backend": {
+			Aliases:    []string{"api"},
+			IPAMConfig: &network.EndpointIPAMConfig{IPv4Address: "172.21.0.10"},
+			IPAddress:  "172.21.0.10",
+			MacAddress: "02:42:ac:15:00:0a",
+			Links:      []string{"db:database"},
+		},
+	}
+	return web
+}
+
+func TestEndpointAliases(t *testing.T) {
+	web := multiNetworkContainer()
+	// 容器名称、短 ID 和重复的别名由 docker 自动添加或重复记录，不导出
+	if got, want := endpointAliases(web, web.NetworkSettings.Networks["app"]), []string{"frontend"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("aliases = %q, want %q", got, want)
+	}
+}
+
+func TestMultipleNetworksExport(t *testing.T) {
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"command", []string{
+			"docker create --name web --network app --network-alias frontend --ip 172.20.0.10 --ip6 fd00::10 --mac-address 92:d0:c6:0a:29:33 nginx:latest\n",
+			"docker network connect --alias api --ip 172.21.0.10 --link db:database backend web\n",
+			"docker start web",
+		}},
+		{"compose", []string{
+			"      app:\n        aliases:\n          - frontend\n        ipv4_address: 172.20.0.10\n        ipv6_address: \"fd00::10\"\n        mac_address: \"92:d0:c6:0a:29:33\"\n",
+			"      backend:\n        aliases:\n          - api\n        ipv4_address: 172.21.0.10\n",
+		}},
+		{"terraform", []string{
+			"    name = docker_network.app.name\n    aliases = [\"frontend\"]\n    ipv4_address = \"172.20.0.10\"\n    ipv6_address = \"fd00::10\"\n",
+			"    name = docker_network.backend.name\n    aliases = [\"api\"]\n    ipv4_address = \"172.21.0.10\"\n",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			out := exportString(t, []types.ContainerJSON{multiNetworkContainer()}, &ExportOptions{Format: tt.format, SingleFile: true})
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("missing %q in:\n%s", want, out)
+				}
+			}
+			// docker 根据 IP 生成的 MAC 地址不导出
+			if strings.Contains(out, "02:42:ac:15:00:0a") {
+				t.Errorf("generated mac address is exported:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestMultipleNetworksRoundTrip(t *testing.T) {
+	script := exportString(t, []types.ContainerJSON{multiNetworkContainer()}, &ExportOptions{Format: "command"})
+	specs, err := loadTestScript(t, script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints := specs[0].NetworkingConfig.EndpointsConfig
+	app, backend := endpoints["app"], endpoints["backend"]
+	if app == nil || backend == nil {
+		t.Fatalf("endpoints = %v, want app and backend", endpoints)
+	}
+	if !reflect.DeepEqual(app.Aliases, []string{"frontend"}) || app.IPAMConfig == nil || app.IPAMConfig.IPv4Address != "172.20.0.10" || app.MacAddress != "92:d0:c6:0a:29:33" {
+		t.Errorf("app endpoint = %+v", app)
+	}
+	if !reflect.DeepEqual(backend.Aliases, []string{"api"}) || backend.IPAMConfig == nil || backend.IPAMConfig.IPv4Address != "172.21.0.10" {
+		t.Errorf("backend endpoint = %+v", backend)
+	}
+}
+
+// appNetwork 返回 NetworkInspect 结果中的自定义网络，包含 compose 自动添加的标签
+func appNetwork() network.Inspect {
+	return network.Inspect{
+		Name:       "app",
+		ID:         "0123456789abcdef",
+		Driver:     "bridge",
+		Internal:   true,
+		Attachable: true,
+		EnableIPv6: true,
+		IPAM: network.IPAM{
+			Driver:  "default",
+			Config:  []network.IPAMConfig{{Subnet: "172.20.0.0/16", IPRange: "172.20.5.0/24", Gateway: "172.20.0.1"}},
+			Options: map[string]string{"foo": "bar"},
+		},
+		Options: map[string]string{"com.docker.network.bridge.name": "br-app"},
+		Labels:  map[string]string{"com.docker.compose.network": "app", "team": "web"},
+	}
+}
+
+func TestNetworkCreateArgs(t *testing.T) {
+	want := []string{
+		"--subnet", "172.20.0.0/16", "--ip-range", "172.20.5.0/24", "--gateway", "172.20.0.1",
+		"--ipam-opt", "foo=bar", "--internal", "--attachable", "--ipv6",
+		"--opt", "com.docker.network.bridge.name=br-app", "--label", "team=web", "app",
+	}
+	if got := networkCreateArgs(appNetwork()); !reflect.DeepEqual(got, want) {
+		t.Errorf("args = %q\nwant %q", got, want)
+	}
+	overlay := network.Inspect{Name: "swarm", Driver: "overlay", IPAM: network.IPAM{Driver: "custom"}}
+	if got, want := networkCreateArgs(overlay), []string{"--driver", "overlay", "--ipam-driver", "custom", "swarm"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+}
+
+func TestNetworkDefinitions(t *testing.T) {
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"command", []string{
+			"# Network: app\ndocker network create --subnet 172.20.0.0/16 --ip-range 172.20.5.0/24 --gateway 172.20.0.1 --ipam-opt foo=bar --internal --attachable --ipv6 --opt com.docker.network.bridge.name=br-app --label team=web app\n",
+		}},
+		{"compose", []string{
+			"networks:\n  app:\n    name: app\n    driver: bridge\n",
+			"    internal: true\n    attachable: true\n    enable_ipv6: true\n",
+			"    ipam:\n      config:\n        - subnet: \"172.20.0.0/16\"\n          ip_range: \"172.20.5.0/24\"\n          gateway: \"172.20.0.1\"\n",
+			"    labels:\n      team: web\n",
+			// 没有网络详细信息时引用已存在的网络
+			"  backend:\n    name: backend\n    external: true\n",
+		}},
+		{"quadlet", []string{
+			"[Network]\nNetworkName=app\nDriver=bridge\nInternal=true\nIPv6=true\nSubnet=172.20.0.0/16\nGateway=172.20.0.1\nIPRange=172.20.5.0/24\n",
+			"Label=team=web\n",
+		}},
+		{"terraform", []string{
+			"resource \"docker_network\" \"app\" {\n  name = \"app\"\n  driver = \"bridge\"\n  internal = true\n  attachable = true\n  ipv6 = true\n",
+			"  ipam_options = {\n    \"foo\" = \"bar\"\n  }\n",
+			"    ip_range = \"172.20.5.0/24\"\n",
+			"  labels {\n    label = \"team\"\n    value = \"web\"\n  }\n",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			opts := &ExportOptions{Format: tt.format, SingleFile: true, Networks: map[string]network.Inspect{"app": appNetwork()}}
+			out := exportString(t, []types.ContainerJSON{multiNetworkContainer()}, opts)
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("missing %q in:\n%s", want, out)
+				}
+			}
+			if strings.Contains(out, "com.docker.compose.network") {
+				t.Errorf("compose labels are exported:\n%s", out)
+			}
+		})
+	}
+}
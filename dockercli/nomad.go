@@ -0,0 +1,304 @@
+package dockercli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/fimreal/goutils/ezap"
+)
+
+// nomadJobName 导出的 nomad job 名称
+const nomadJobName = "docker"
+
+// Containers2Nomad 将容器转换为一个 nomad job，每个容器对应一个使用 docker driver 的 group 和 task
+func Containers2Nomad(containersJSON []types.ContainerJSON) (Files, error) {
+	var job strings.Builder
+	job.WriteString(fmt.Sprintf("job %s {\n", hclQuote(nomadJobName)))
+	job.WriteString("  datacenters = [\"dc1\"]\n")
+	job.WriteString("  type        = \"service\"\n")
+	for _, containerJSON := range containersJSON {
+		job.WriteString("\n")
+		job.WriteString(generateNomadGroup(containerJSON))
+	}
+	job.WriteString("}\n")
+	return Files{nomadJobName + ".nomad.hcl": job.String()}, nil
+}
+
+// generateNomadGroup 生成单个容器的 group
+func generateNomadGroup(containerJSON types.ContainerJSON) string {
+	var group strings.Builder
+	cname := strings.TrimPrefix(containerJSON.Name, "/")
+	config := containerJSON.Config
+	hostConfig := containerJSON.HostConfig
+
+	group.WriteString(fmt.Sprintf("  group %s {\n", hclQuote(cname)))
+	group.WriteString("    count = 1\n")
+
+	// port mapping，nomad 中的端口需要在 group 中声明，task 通过标签引用
+	var labels []string
+	added := make(map[string]bool)
+	var network strings.Builder
+	for _, port := range containerPorts(containerJSON) {
+		for _, binding := range containerJSON.NetworkSettings.Ports[port] {
+			if binding.HostPort == "" {
+				continue
+			}
+			label := port.Proto() + "_" + binding.HostPort
+			if added[label] {
+				continue
+			}
+			added[label] = true
+			labels = append(labels, label)
+			network.WriteString(fmt.Sprintf("      port %s {\n", hclQuote(label)))
+			network.WriteString(fmt.Sprintf("        static = %s\n", binding.HostPort))
+			network.WriteString(fmt.Sprintf("        to     = %d\n", port.Int()))
+			network.WriteString("      }\n")
+		}
+	}
+	if network.Len() > 0 {
+		group.WriteString("\n    network {\n")
+		group.WriteString(network.String())
+		group.WriteString("    }\n")
+	}
+
+	// restart policy
+	switch hostConfig.RestartPolicy.Name {
+	case container.RestartPolicyDisabled:
+		group.WriteString("\n    restart {\n")
+		group.WriteString("      attempts = 0\n")
+		group.WriteString("      mode     = \"fail\"\n")
+		group.WriteString("    }\n")
+	case container.RestartPolicyOnFailure:
+		if hostConfig.RestartPolicy.MaximumRetryCount > 0 {
+			group.WriteString("\n    restart {\n")
+			group.WriteString(fmt.Sprintf("      attempts = %d\n", hostConfig.RestartPolicy.MaximumRetryCount))
+			group.WriteString("      mode     = \"fail\"\n")
+			group.WriteString("    }\n")
+		}
+	}
+
+	group.WriteString(fmt.Sprintf("\n    task %s {\n", hclQuote(cname)))
+	group.WriteString("      driver = \"docker\"\n")
+	if config.User != "" {
+		group.WriteString(fmt.Sprintf("      user   = %s\n", hclQuote(config.User)))
+	}
+
+	group.WriteString("\n      config {\n")
+	group.WriteString(fmt.Sprintf("        image = %s\n", hclQuote(config.Image)))
+
+	// hostname
+	if hostname := customHostname(containerJSON); hostname != "" {
+		group.WriteString(fmt.Sprintf("        hostname = %s\n", hclQuote(hostname)))
+	}
+
+	// entrypoint
+	if len(config.Entrypoint) > 0 {
+		group.WriteString(fmt.Sprintf("        entrypoint = %s\n", hclList(config.Entrypoint)))
+	}
+
+	// command
+	if len(config.Cmd) > 0 {
+		group.WriteString(fmt.Sprintf("        command = %s\n", hclQuote(config.Cmd[0])))
+		if len(config.Cmd) > 1 {
+			group.WriteString(fmt.Sprintf("        args    = %s\n", hclList(config.Cmd[1:])))
+		}
+	}
+
+	// workdir
+	if config.WorkingDir != "" {
+		group.WriteString(fmt.Sprintf("        work_dir = %s\n", hclQuote(config.WorkingDir)))
+	}
+
+	// ports
+	if len(labels) > 0 {
+		group.WriteString(fmt.Sprintf("        ports = %s\n", hclList(labels)))
+	}
+
+	// network mode
+	if target, found := strings.CutPrefix(string(hostConfig.NetworkMode), "container:"); found {
+		ezap.Warnf("%s: shares the network namespace of container %s, which is not supported by nomad across groups", cname, target)
+	} else if mode := hostConfig.NetworkMode; mode.IsHost() || mode.IsNone() {
+		group.WriteString(fmt.Sprintf("        network_mode = %s\n", hclQuote(string(mode))))
+		if networks := userNetworks(containerJSON); len(networks) > 0 {
+			ezap.Warnf("%s: networks %s are dropped, network_mode %s does not allow other networks", cname, strings.Join(networks, ", "), mode)
+		}
+	} else if networks := userNetworks(containerJSON); len(networks) > 0 {
+		// docker driver 只能指定一个网络
+		if len(networks) > 1 {
+			ezap.Warnf("%s: only network %s is kept, nomad docker driver supports a single network", cname, networks[0])
+		}
+		group.WriteString(fmt.Sprintf("        network_mode = %s\n", hclQuote(networks[0])))
+	}
+
+	// privileged mode
+	if hostConfig.Privileged {
+		group.WriteString("        privileged = true\n")
+	}
+
+	// capabilities
+	if len(hostConfig.CapAdd) > 0 {
+		group.WriteString(fmt.Sprintf("        cap_add = %s\n", hclList(lowerAll(hostConfig.CapAdd))))
+	}
+	if len(hostConfig.CapDrop) > 0 {
+		group.WriteString(fmt.Sprintf("        cap_drop = %s\n", hclList(lowerAll(hostConfig.CapDrop))))
+	}
+
+	// readonly root fs
+	if hostConfig.ReadonlyRootfs {
+		group.WriteString("        readonly_rootfs = true\n")
+	}
+
+	// pid and ipc mode
+	if hostConfig.PidMode.IsHost() {
+		group.WriteString("        pid_mode = \"host\"\n")
+	}
+	if hostConfig.IpcMode.IsHost() {
+		group.WriteString("        ipc_mode = \"host\"\n")
+	}
+
+	// host-to-IP mapping
+	if len(hostConfig.ExtraHosts) > 0 {
+		group.WriteString(fmt.Sprintf("        extra_hosts = %s\n", hclList(hostConfig.ExtraHosts)))
+	}
+
+	// dns
+	if len(hostConfig.DNS) > 0 {
+		group.WriteString(fmt.Sprintf("        dns_servers = %s\n", hclList(hostConfig.DNS)))
+	}
+
+	// --cpus 是硬限制，nomad 的 cpu 资源默认只是份额
+	if hostConfig.NanoCPUs > 0 {
+		group.WriteString("        cpu_hard_limit = true\n")
+	}
+
+	// pids limit
+	if hostConfig.PidsLimit != nil && *hostConfig.PidsLimit > 0 {
+		group.WriteString(fmt.Sprintf("        pids_limit = %d\n", *hostConfig.PidsLimit))
+	}
+
+	// labels
+	if len(config.Labels) > 0 {
+		// 使用 map 语法，block 语法中的键不能包含 . 等字符
+		group.WriteString("\n        labels = {\n")
+		for _, key := range sortedKeys(config.Labels) {
+			group.WriteString(fmt.Sprintf("          %s = %s\n", hclQuote(key), hclQuote(config.Labels[key])))
+		}
+		group.WriteString("        }\n")
+	}
+
+	// mount
+	for _, mount := range containerJSON.Mounts {
+		var source string
+		switch {
+		case mount.Type == "bind":
+			source = mount.Source
+		case mount.Type == "volume" && mount.Name != "" && !isAnonymousVolume(mount.Name):
+			source = mount.Name
+		case mount.Type == "volume":
+			// 匿名卷不指定 source，由 docker 创建
+		default:
+			continue
+		}
+		group.WriteString("\n        mount {\n")
+		group.WriteString(fmt.Sprintf("          type     = %s\n", hclQuote(string(mount.Type))))
+		if source != "" {
+			group.WriteString(fmt.Sprintf("          source   = %s\n", hclQuote(source)))
+		}
+		group.WriteString(fmt.Sprintf("          target   = %s\n", hclQuote(mount.Destination)))
+		group.WriteString(fmt.Sprintf("          readonly = %t\n", !mount.RW))
+		group.WriteString("        }\n")
+	}
+
+	// log driver
+	if hostConfig.LogConfig.Type != "" && hostConfig.LogConfig.Type != "json-file" || len(hostConfig.LogConfig.Config) > 0 {
+		group.WriteString("\n        logging {\n")
+		group.WriteString(fmt.Sprintf("          type = %s\n", hclQuote(hostConfig.LogConfig.Type)))
+		if len(hostConfig.LogConfig.Config) > 0 {
+			// 与 labels 一样使用 map 语法，日志选项的键可能包含 . 等字符
+			group.WriteString("          config = {\n")
+			for _, key := range sortedKeys(hostConfig.LogConfig.Config) {
+				group.WriteString(fmt.Sprintf("            %s = %s\n", hclQuote(key), hclQuote(hostConfig.LogConfig.Config[key])))
+			}
+			group.WriteString("          }\n")
+		}
+		group.WriteString("        }\n")
+	}
+	group.WriteString("      }\n")
+
+	// environment variables
+	var env []string
+	for _, e := range config.Env {
+		if e == "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin" || !strings.Contains(e, "=") {
+			continue
+		}
+		env = append(env, e)
+	}
+	if len(env) > 0 {
+		group.WriteString("\n      env = {\n")
+		for _, e := range env {
+			key, value, _ := strings.Cut(e, "=")
+			group.WriteString(fmt.Sprintf("        %s = %s\n", hclQuote(key), hclQuote(value)))
+		}
+		group.WriteString("      }\n")
+	}
+
+	// resources，内存软限制对应 nomad 调度时预留的 memory，硬限制对应 memory_max
+	if hostConfig.NanoCPUs > 0 || hostConfig.Memory > 0 || hostConfig.MemoryReservation > 0 {
+		group.WriteString("\n      resources {\n")
+		if hostConfig.NanoCPUs > 0 {
+			// nomad 的 cpu 单位为 MHz，按照 1 个 CPU 等于 1000 MHz 换算
+			group.WriteString(fmt.Sprintf("        cpu        = %d\n", hostConfig.NanoCPUs/1e6))
+		}
+		switch {
+		case hostConfig.MemoryReservation > 0 && hostConfig.Memory > 0:
+			group.WriteString(fmt.Sprintf("        memory     = %d\n", nomadMemory(hostConfig.MemoryReservation)))
+			group.WriteString(fmt.Sprintf("        memory_max = %d\n", nomadMemory(hostConfig.Memory)))
+		case hostConfig.MemoryReservation > 0:
+			group.WriteString(fmt.Sprintf("        memory     = %d\n", nomadMemory(hostConfig.MemoryReservation)))
+		case hostConfig.Memory > 0:
+			group.WriteString(fmt.Sprintf("        memory     = %d\n", nomadMemory(hostConfig.Memory)))
+		}
+		group.WriteString("      }\n")
+	}
+	resources := *hostConfig
+	resources.NanoCPUs, resources.Memory, resources.MemoryReservation, resources.PidsLimit = 0, 0, 0, nil
+	for _, option := range resourceOptions(&resources) {
+		ezap.Warnf("%s: %s is not supported by nomad, dropped", cname, option[0])
+	}
+
+	group.WriteString("    }\n")
+	group.WriteString("  }\n")
+	return group.String()
+}
+
+// nomadMemory 将字节数换算为 nomad 使用的 MiB，不足 1 MiB 时按 1 MiB 计算
+func nomadMemory(bytes int64) int64 {
+	return max(bytes>>20, 1)
+}
+
+// hclQuote 返回 HCL 字符串，JSON 字符串的转义方式与 HCL 兼容，
+// ${ 和 %{ 写成 $${ 和 %%{ 避免被当作模板展开
+func hclQuote(s string) string {
+	quoted := yamlQuote(s)
+	return strings.NewReplacer("${", "$${", "%{", "%%{").Replace(quoted)
+}
+
+// hclList 生成 ["a", "b"] 形式的 HCL 列表
+func hclList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = hclQuote(item)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// lowerAll 将列表中的字符串转换为小写
+func lowerAll(items []string) []string {
+	lowered := make([]string, len(items))
+	for i, item := range items {
+		lowered[i] = strings.ToLower(item)
+	}
+	return lowered
+}
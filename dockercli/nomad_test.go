@@ -0,0 +1,62 @@
+package dockercli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+)
+
+func TestNomadNetworkMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     container.NetworkMode
+		networks []string
+		want     string
+	}{
+		{"host with user networks", "host", []string{"host", "app"}, `network_mode = "host"`},
+		{"none", "none", []string{"none"}, `network_mode = "none"`},
+		{"user network", "app", []string{"app", "backend"}, `network_mode = "app"`},
+		{"container", "container:bbbbbbbbbbbb2222", []string{"app"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			web := testContainer("aaaaaaaaaaaa1111", "web")
+			web.HostConfig.NetworkMode = tt.mode
+			web.NetworkSettings.Networks = make(map[string]*network.EndpointSettings)
+			for _, name := range tt.networks {
+				web.NetworkSettings.Networks[name] = &network.EndpointSettings{}
+			}
+			containers := []types.ContainerJSON{web}
+			if tt.mode.IsContainer() {
+				containers = append(containers, testContainer("bbbbbbbbbbbb2222", "db"))
+			}
+			job := exportString(t, containers, &ExportOptions{Format: "nomad"})
+			count := strings.Count(job, "network_mode =")
+			if tt.want == "" && count != 0 || tt.want != "" && (count != 1 || !strings.Contains(job, tt.want)) {
+				t.Errorf("want a single %q, got %d network_mode attributes:\n%s", tt.want, count, job)
+			}
+		})
+	}
+}
+
+func TestNomadLoggingConfig(t *testing.T) {
+	web := testContainer("aaaaaaaaaaaa1111", "web")
+	web.HostConfig.LogConfig = container.LogConfig{Type: "gelf", Config: map[string]string{
+		"gelf-address": "udp://graylog:12201",
+		"labels":       "app,env",
+		"tag":          "{{.Name}}/${x}",
+	}}
+	job := exportString(t, []types.ContainerJSON{web}, &ExportOptions{Format: "nomad"})
+	want := `          config = {
+            "gelf-address" = "udp://graylog:12201"
+            "labels" = "app,env"
+            "tag" = "{{.Name}}/$${x}"
+          }
+`
+	if !strings.Contains(job, want) {
+		t.Errorf("logging config not written as a quoted map:\n%s", job)
+	}
+}
@@ -3,10 +3,15 @@ package dockercli
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
 	"github.com/fimreal/goutils/ezap"
 )
 
@@ -19,20 +24,42 @@ func Containers2JSON(containers []types.ContainerJSON) string {
 	return string(output)
 }
 
-// Containers2CMD 将容器详细信息打印为 docker run 格式
-func Containers2CMD(containersJSON []types.ContainerJSON, pretty bool) string {
+// Containers2CMD 将容器详细信息打印为 docker run 格式，按依赖关系排序，被依赖的容器在前，
+// 容器用到的自定义网络和命名卷在 opts.External 为 false 时生成 docker network create、docker volume create 命令
+func Containers2CMD(containersJSON []types.ContainerJSON, opts *ExportOptions) (string, error) {
+	graph, err := buildContainerGraph(containersJSON)
+	if err != nil {
+		return "", err
+	}
+
 	var command strings.Builder
-	for c, containerJSON := range containersJSON {
+	if !opts.External {
+		for _, name := range referencedNetworks(containersJSON) {
+			networkJSON, ok := opts.Networks[name]
+			if !ok {
+				continue
+			}
+			command.WriteString(fmt.Sprintf("# Network: %s\n", shellComment(name)))
+			command.WriteString("docker network create " + shellCommand(networkCreateArgs(networkJSON)) + "\n\n")
+		}
+		for _, mount := range referencedVolumes(containersJSON) {
+			command.WriteString(fmt.Sprintf("# Volume: %s\n", shellComment(mount.Name)))
+			command.WriteString("docker volume create " + shellCommand(volumeCreateArgs(volumeDefinition(mount, opts.Volumes))) + "\n\n")
+		}
+	}
+
+	for c, containerJSON := range graph.sortContainers(containersJSON) {
 		if c != 0 {
 			command.WriteString("\n\n")
 		}
-		command.WriteString(buildDockerRunCommand(containerJSON, pretty))
+		command.WriteString(buildDockerRunCommand(containerJSON, graph, opts.Pretty, opts.envFiles[strings.TrimPrefix(containerJSON.Name, "/")]))
 	}
-	return command.String()
+	return command.String(), nil
 }
 
-// buildDockerRunCommand 构建 docker run 命令字符串
-func buildDockerRunCommand(containerJSON types.ContainerJSON, pretty bool) string {
+// buildDockerRunCommand 构建 docker run 命令字符串，graph 用于将其他容器的引用转换为容器名称，
+// envFile 不为空时通过 --env-file 引用环境变量文件
+func buildDockerRunCommand(containerJSON types.ContainerJSON, graph *containerGraph, pretty bool, envFile string) string {
 	var command strings.Builder
 	end := " "
 	if pretty {
@@ -43,325 +70,1147 @@ func buildDockerRunCommand(containerJSON types.ContainerJSON, pretty bool) strin
 	created := containerJSON.Created
 
 	// description
-	command.WriteString(fmt.Sprintf("# Container name: %s\n", cname))
+	command.WriteString(fmt.Sprintf("# Container name: %s\n", shellComment(cname)))
 	command.WriteString(fmt.Sprintf("# Created at: %s\n", created))
-	command.WriteString(fmt.Sprintf("# Description: %s\n", containerJSON.Config.Labels["description"]))
+	command.WriteString(fmt.Sprintf("# Description: %s\n", shellComment(containerJSON.Config.Labels["description"])))
+
+	// 连接多个网络时先创建容器，连接其余网络之后再启动
+	networks := extraNetworks(containerJSON)
+	if len(networks) == 0 {
+		command.WriteString("docker run -d" + end)
+		command.WriteString(buildRunCommand(containerJSON, graph, envFile).render(shellQuote, end))
+		return command.String()
+	}
+
+	command.WriteString("docker create" + end)
+	command.WriteString(buildRunCommand(containerJSON, graph, envFile).render(shellQuote, end))
+	for _, name := range networks {
+		command.WriteString("\ndocker network connect " + shellCommand(networkConnectArgs(containerJSON, name)))
+	}
+	command.WriteString("\ndocker start " + shellQuote(cname))
+
+	return command.String()
+}
+
+// runCommand docker run 命令中的参数、镜像和镜像之后的命令，均未加引号
+type runCommand struct {
+	Options [][]string // 每一项为一个参数及其取值
+	Image   string
+	Args    []string
+}
+
+// add 添加一个参数
+func (r *runCommand) add(words ...string) {
+	r.Options = append(r.Options, words)
+}
 
-	// docker run
-	command.WriteString("docker run -d" + end)
+// render 使用 quote 为每个单词加引号后拼接成命令，sep 为参数之间的分隔符
+func (r runCommand) render(quote func(string) string, sep string) string {
+	var command strings.Builder
+	for _, option := range r.Options {
+		for i, word := range option {
+			if i > 0 {
+				command.WriteString(" ")
+			}
+			command.WriteString(quote(word))
+		}
+		command.WriteString(sep)
+	}
+	command.WriteString(quote(r.Image))
+	if len(r.Args) > 0 {
+		command.WriteString(sep)
+		for i, arg := range r.Args {
+			if i > 0 {
+				command.WriteString(" ")
+			}
+			command.WriteString(quote(arg))
+		}
+	}
+	return command.String()
+}
+
+// buildRunCommand 生成 docker run 的参数，不包含 docker run 和 -d
+func buildRunCommand(containerJSON types.ContainerJSON, graph *containerGraph, envFile string) runCommand {
+	var command runCommand
+	cname := strings.TrimPrefix(containerJSON.Name, "/")
 
 	// container name
-	command.WriteString("--name " + cname + end)
+	command.add("--name", cname)
 
 	// hostname
-	hostname := containerJSON.Config.Hostname
-	csha := containerJSON.ID[:12]
-	if hostname != csha {
-		command.WriteString("--hostname " + hostname + end)
+	if hostname := customHostname(containerJSON); hostname != "" {
+		command.add("--hostname", hostname)
 	}
 
 	// restart policy
 	if containerJSON.HostConfig.RestartPolicy.Name != "" && containerJSON.HostConfig.RestartPolicy.Name != "no" {
-		command.WriteString(fmt.Sprintf("--restart %s%s", containerJSON.HostConfig.RestartPolicy.Name, end))
+		restart := string(containerJSON.HostConfig.RestartPolicy.Name)
 		if containerJSON.HostConfig.RestartPolicy.MaximumRetryCount > 0 {
-			command.WriteString(fmt.Sprintf("--restart-max-attempts %d%s", containerJSON.HostConfig.RestartPolicy.MaximumRetryCount, end))
+			restart += ":" + strconv.Itoa(containerJSON.HostConfig.RestartPolicy.MaximumRetryCount)
 		}
+		command.add("--restart", restart)
 	}
 
 	// user
 	if containerJSON.Config.User != "" {
-		command.WriteString(fmt.Sprintf("--user %s%s", containerJSON.Config.User, end))
+		command.add("--user", containerJSON.Config.User)
 	}
 
 	// workdir
 	if containerJSON.Config.WorkingDir != "" {
-		command.WriteString("--workdir " + containerJSON.Config.WorkingDir + end)
+		command.add("--workdir", containerJSON.Config.WorkingDir)
 	}
 
 	// entrypoint
+	// --entrypoint 只能指定一个可执行文件，其余部分放到镜像之后的命令前面
+	var entrypointArgs []string
 	if len(containerJSON.Config.Entrypoint) > 0 {
-		command.WriteString("--entrypoint " + strings.Join(containerJSON.Config.Entrypoint, " ") + end)
+		command.add("--entrypoint", containerJSON.Config.Entrypoint[0])
+		entrypointArgs = containerJSON.Config.Entrypoint[1:]
 	}
 
 	// environment variables
+	if envFile != "" {
+		// 环境变量文件与脚本位于同一目录，在其他目录中执行脚本时也能找到
+		command.add("--env-file", scriptDirMarker+"/"+envFile)
+	}
 	for _, env := range containerJSON.Config.Env {
 		if env == "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin" {
 			continue
 		}
-		command.WriteString(fmt.Sprintf("-e %s%s", env, end))
+		command.add("-e", env)
 	}
 
 	// host-to-IP mapping
 	for _, hosts := range containerJSON.HostConfig.ExtraHosts {
-		command.WriteString("--add-host " + hosts + end)
+		command.add("--add-host", hosts)
 	}
 
 	// privileged mode
 	if containerJSON.HostConfig.Privileged {
-		command.WriteString("--privileged" + end)
+		command.add("--privileged")
 	}
 
 	// capabilities
 	for _, cap := range containerJSON.HostConfig.CapAdd {
-		command.WriteString("--cap-add " + cap + end)
+		command.add("--cap-add", cap)
 	}
 	for _, cap := range containerJSON.HostConfig.CapDrop {
-		command.WriteString("--cap-drop " + cap + end)
+		command.add("--cap-drop", cap)
 	}
 
 	// readonly root fs
 	if containerJSON.HostConfig.ReadonlyRootfs {
-		command.WriteString("--read-only " + end)
+		command.add("--read-only")
+	}
+
+	// security options
+	for _, opt := range securityOptions(containerJSON.HostConfig) {
+		command.add("--security-opt", opt)
+	}
+
+	// supplementary groups
+	for _, group := range containerJSON.HostConfig.GroupAdd {
+		command.add("--group-add", group)
 	}
 
 	// OOM score adjustment
 	if containerJSON.HostConfig.OomScoreAdj != 0 {
-		command.WriteString("--oom-score-adj=" + strconv.Itoa(containerJSON.HostConfig.OomScoreAdj) + end)
+		command.add("--oom-score-adj", strconv.Itoa(containerJSON.HostConfig.OomScoreAdj))
 	}
 
 	// user namespace mode
 	if containerJSON.HostConfig.UsernsMode != "" {
-		command.WriteString(fmt.Sprintf("--userns-mode=%s%s", containerJSON.HostConfig.UsernsMode, end))
+		command.add("--userns", string(containerJSON.HostConfig.UsernsMode))
+	}
+
+	// uts mode
+	if containerJSON.HostConfig.UTSMode.IsHost() {
+		command.add("--uts", "host")
+	}
+
+	// cgroup namespace mode
+	if containerJSON.HostConfig.CgroupnsMode != "" {
+		command.add("--cgroupns", string(containerJSON.HostConfig.CgroupnsMode))
+	}
+
+	// pid mode
+	if ref, ok := graph.ref(cname, refPid); ok {
+		command.add("--pid", "container:"+ref.Target)
+	} else if containerJSON.HostConfig.PidMode != "" {
+		command.add("--pid", string(containerJSON.HostConfig.PidMode))
 	}
 
 	// ipc mode
-	if containerJSON.HostConfig.PidMode != "" {
-		command.WriteString(fmt.Sprintf("--pid %s%s", containerJSON.HostConfig.PidMode, end))
+	if ref, ok := graph.ref(cname, refIpc); ok {
+		command.add("--ipc", "container:"+ref.Target)
+	} else if ipcMode := containerJSON.HostConfig.IpcMode; ipcMode.IsHost() || ipcMode.IsNone() {
+		command.add("--ipc", string(ipcMode))
 	}
 
 	// link
-	for _, link := range containerJSON.HostConfig.Links {
-		linkParts := strings.Split(link, ":")
-		sourceContainer := strings.TrimPrefix(linkParts[0], "/")
-		if len(linkParts) == 2 {
-			aliasName := linkParts[1]
-			command.WriteString(fmt.Sprintf("--link %s:%s%s", sourceContainer, aliasName, end))
+	for _, ref := range graph.refsOf(cname, refLink) {
+		if ref.Option != "" {
+			command.add("--link", ref.Target+":"+ref.Option)
+		} else {
+			command.add("--link", ref.Target)
+		}
+	}
+
+	// volumes from
+	for _, ref := range graph.refsOf(cname, refVolumesFrom) {
+		if ref.Option != "" {
+			command.add("--volumes-from", ref.Target+":"+ref.Option)
 		} else {
-			command.WriteString(fmt.Sprintf("--link %s%s", sourceContainer, end))
+			command.add("--volumes-from", ref.Target)
 		}
 	}
 
-	// cpu limit
-	if containerJSON.HostConfig.NanoCPUs > 0 {
-		// Convert NanoCPUs to a value for --cpus
-		cpuLimit := float64(containerJSON.HostConfig.NanoCPUs) / 1e9 // convert to CPUs
-		command.WriteString(fmt.Sprintf("--cpus=%.2f%s", cpuLimit, end))
+	// cpu, memory and block io limits
+	for _, option := range resourceOptions(containerJSON.HostConfig) {
+		command.add(option...)
+	}
+
+	// OOM killer
+	if oomKillDisable := containerJSON.HostConfig.OomKillDisable; oomKillDisable != nil && *oomKillDisable {
+		command.add("--oom-kill-disable")
 	}
 
-	// cpu shares
-	if containerJSON.HostConfig.CPUShares > 0 {
-		command.WriteString(fmt.Sprintf("--cpu-shares=%d%s", containerJSON.HostConfig.CPUShares, end))
+	// shared memory size
+	if shmSize := containerJSON.HostConfig.ShmSize; shmSize > 0 && shmSize != defaultShmSize {
+		command.add("--shm-size", bytesSize(shmSize))
 	}
 
-	// cpuset
-	if containerJSON.HostConfig.CpusetCpus != "" {
-		command.WriteString("--cpuset-cpus " + containerJSON.HostConfig.CpusetCpus + end)
+	// ulimits
+	for _, ulimit := range containerJSON.HostConfig.Ulimits {
+		command.add("--ulimit", ulimitSpec(ulimit))
 	}
 
-	// memory limit
-	if containerJSON.HostConfig.Memory > 0 {
-		command.WriteString("--memory=" + strconv.FormatInt(containerJSON.HostConfig.Memory, 10) + end)
+	// sysctls
+	for _, key := range sortedKeys(containerJSON.HostConfig.Sysctls) {
+		command.add("--sysctl", key+"="+containerJSON.HostConfig.Sysctls[key])
+	}
+
+	// tmpfs
+	for _, path := range sortedKeys(containerJSON.HostConfig.Tmpfs) {
+		command.add("--tmpfs", tmpfsSpec(path, containerJSON.HostConfig.Tmpfs[path]))
+	}
+
+	// storage options
+	for _, key := range sortedKeys(containerJSON.HostConfig.StorageOpt) {
+		command.add("--storage-opt", key+"="+containerJSON.HostConfig.StorageOpt[key])
 	}
 
 	// network mode
-	if containerJSON.HostConfig.NetworkMode != "" && containerJSON.HostConfig.NetworkMode != "default" {
-		command.WriteString(fmt.Sprintf("--network %s%s", containerJSON.HostConfig.NetworkMode, end))
+	if ref, ok := graph.ref(cname, refNetwork); ok {
+		command.add("--network", "container:"+ref.Target)
+	} else if containerJSON.HostConfig.NetworkMode != "" && containerJSON.HostConfig.NetworkMode != "default" {
+		command.add("--network", string(containerJSON.HostConfig.NetworkMode))
+	}
+
+	// network aliases, static ip and mac address
+	primary := primaryNetwork(containerJSON)
+	for _, option := range endpointOptions(containerJSON, primary, networkEndpoint(containerJSON, primary), true) {
+		command.add(option...)
 	}
 
 	// dns
 	for _, dns := range containerJSON.HostConfig.DNS {
-		command.WriteString("--dns " + dns + end)
+		command.add("--dns", dns)
 	}
 
 	// port mapping
 	addedPorts := make(map[string]bool)
-	for port, bindings := range containerJSON.NetworkSettings.Ports {
-		for _, binding := range bindings {
-			var portMapping string
-
-			if binding.HostIP != "0.0.0.0" && binding.HostIP != "" && binding.HostIP != "::" {
-				portMapping = fmt.Sprintf("%s:%s:%s", binding.HostIP, binding.HostPort, port.Port())
-			} else {
-				portMapping = fmt.Sprintf("%s:%s", binding.HostPort, port.Port())
-			}
+	for _, port := range containerPorts(containerJSON) {
+		for _, binding := range containerJSON.NetworkSettings.Ports[port] {
+			portMapping := portSpec(port, binding)
 
 			// Check if the port mapping has already been added
 			if _, exists := addedPorts[portMapping]; !exists {
-				command.WriteString("-p " + portMapping + end)
+				command.add("-p", portMapping)
 				addedPorts[portMapping] = true // Mark as added
 			}
 		}
 	}
 
+	// exposed ports without a published binding
+	for _, port := range exposedOnlyPorts(containerJSON) {
+		command.add("--expose", string(port))
+	}
+
 	// mount
 	for _, mount := range containerJSON.Mounts {
-		if mount.Type == "bind" {
-			// Bind mount
-			command.WriteString(fmt.Sprintf("-v %s:%s%s", mount.Source, mount.Destination, end))
-		} else {
-			// Volume mount
-			command.WriteString(fmt.Sprintf("--mount type=%s,source=%s,target=%s%s", mount.Type, mount.Source, mount.Destination, end))
+		if m := hostMount(containerJSON, mount.Destination); m != nil {
+			// 通过 --mount 创建的挂载保留完整的选项
+			if m.BindOptions != nil && m.BindOptions.CreateMountpoint {
+				ezap.Warnf("%s: docker run --mount does not create a missing host path, create %s before running", cname, m.Source)
+			}
+			command.add("--mount", mountSpec(*m))
+			continue
+		}
+		switch mount.Type {
+		case "bind", "volume":
+			command.add("-v", shortMountSpec(mount))
+		default:
+			command.add("--mount", mountOptions("type="+string(mount.Type), "source="+mount.Source, "target="+mount.Destination))
 		}
 	}
 
 	// devices
 	for _, device := range containerJSON.HostConfig.Devices {
-		command.WriteString(fmt.Sprintf("--device %s:%s%s", device.PathOnHost, device.PathInContainer, end))
+		command.add("--device", device.PathOnHost+":"+device.PathInContainer)
 	}
 
 	// label
-	for key, value := range containerJSON.Config.Labels {
-		command.WriteString(fmt.Sprintf("--label %s=%s%s", key, value, end))
+	for _, key := range sortedKeys(containerJSON.Config.Labels) {
+		command.add("--label", key+"="+containerJSON.Config.Labels[key])
 	}
 
 	// log driver
 	if containerJSON.HostConfig.LogConfig.Type != "" {
 		if containerJSON.HostConfig.LogConfig.Type != "json-file" {
-			command.WriteString(fmt.Sprintf("--log-driver %s%s", containerJSON.HostConfig.LogConfig.Type, end))
+			command.add("--log-driver", containerJSON.HostConfig.LogConfig.Type)
 		}
 	}
-	if containerJSON.HostConfig.LogConfig.Config != nil {
-		for key, value := range containerJSON.HostConfig.LogConfig.Config {
-			command.WriteString(fmt.Sprintf("--log-opt %s=%s%s", key, value, end))
+	for _, key := range sortedKeys(containerJSON.HostConfig.LogConfig.Config) {
+		command.add("--log-opt", key+"="+containerJSON.HostConfig.LogConfig.Config[key])
+	}
+
+	// healthcheck
+	if healthcheck := containerJSON.Config.Healthcheck; healthcheck != nil {
+		switch {
+		case len(healthcheck.Test) > 0 && healthcheck.Test[0] == "NONE":
+			command.add("--no-healthcheck")
+		case len(healthcheck.Test) > 0:
+			command.add("--health-cmd", healthCmd(healthcheck.Test))
+		}
+		if healthcheck.Interval > 0 {
+			command.add("--health-interval", healthcheck.Interval.String())
+		}
+		if healthcheck.Timeout > 0 {
+			command.add("--health-timeout", healthcheck.Timeout.String())
+		}
+		if healthcheck.StartPeriod > 0 {
+			command.add("--health-start-period", healthcheck.StartPeriod.String())
+		}
+		if healthcheck.Retries > 0 {
+			command.add("--health-retries", strconv.Itoa(healthcheck.Retries))
 		}
 	}
 
+	// init
+	if containerJSON.HostConfig.Init != nil && *containerJSON.HostConfig.Init {
+		command.add("--init")
+	}
+
+	// stop signal and timeout
+	if containerJSON.Config.StopSignal != "" {
+		command.add("--stop-signal", containerJSON.Config.StopSignal)
+	}
+	if containerJSON.Config.StopTimeout != nil {
+		command.add("--stop-timeout", strconv.Itoa(*containerJSON.Config.StopTimeout))
+	}
+
 	// image
-	command.WriteString(containerJSON.Config.Image + end)
+	command.Image = containerJSON.Config.Image
 
 	// command
-	if len(containerJSON.Config.Cmd) > 0 {
-		command.WriteString(strings.Join(containerJSON.Config.Cmd, " "))
+	// entrypointArgs 与 Config.Entrypoint 共用底层数组，先复制再追加
+	command.Args = append(append([]string{}, entrypointArgs...), containerJSON.Config.Cmd...)
+
+	return command
+}
+
+// shortID 返回容器的短 ID，ID 不足 12 位时原样返回
+func shortID(id string) string {
+	if len(id) < 12 {
+		return id
 	}
+	return id[:12]
+}
 
-	return command.String()
+// customHostname 返回用户指定的 hostname，未指定时 docker 使用容器短 ID，此时返回空字符串
+func customHostname(containerJSON types.ContainerJSON) string {
+	if hostname := containerJSON.Config.Hostname; hostname != shortID(containerJSON.ID) {
+		return hostname
+	}
+	return ""
+}
+
+// defaultShmSize docker 默认的 /dev/shm 大小
+const defaultShmSize = 64 << 20
+
+// ulimitSpec 返回 name=soft:hard 形式的 ulimit，软限制和硬限制相同时只保留一个值
+func ulimitSpec(ulimit *container.Ulimit) string {
+	if ulimit.Soft == ulimit.Hard {
+		return fmt.Sprintf("%s=%d", ulimit.Name, ulimit.Soft)
+	}
+	return fmt.Sprintf("%s=%d:%d", ulimit.Name, ulimit.Soft, ulimit.Hard)
+}
+
+// tmpfsSpec 返回 path:options 形式的 tmpfs 挂载
+func tmpfsSpec(path, options string) string {
+	if options == "" {
+		return path
+	}
+	return path + ":" + options
+}
+
+// healthCmd 将 healthcheck 的 Test 转换为 --health-cmd 的值。
+// --health-cmd 总是通过 shell 执行，CMD 形式的参数按照 shell 规则加引号后拼接
+func healthCmd(test []string) string {
+	switch test[0] {
+	case "CMD-SHELL":
+		return strings.Join(test[1:], " ")
+	case "CMD":
+		quoted := make([]string, len(test)-1)
+		for i, arg := range test[1:] {
+			quoted[i] = shellQuote(arg)
+		}
+		return strings.Join(quoted, " ")
+	}
+	return strings.Join(test, " ")
+}
+
+// portSpec 生成 [ip:]hostPort:containerPort[/protocol] 形式的端口映射，tcp 协议省略
+func portSpec(port nat.Port, binding nat.PortBinding) string {
+	containerPort := port.Port()
+	if port.Proto() != "tcp" {
+		containerPort += "/" + port.Proto()
+	}
+	if binding.HostIP != "0.0.0.0" && binding.HostIP != "" && binding.HostIP != "::" {
+		return fmt.Sprintf("%s:%s:%s", binding.HostIP, binding.HostPort, containerPort)
+	}
+	return fmt.Sprintf("%s:%s", binding.HostPort, containerPort)
+}
+
+// exposedOnlyPorts 返回容器暴露但没有映射到宿主机的端口
+func exposedOnlyPorts(containerJSON types.ContainerJSON) []nat.Port {
+	var ports []nat.Port
+	for port := range containerJSON.Config.ExposedPorts {
+		if _, ok := containerJSON.HostConfig.PortBindings[port]; ok {
+			continue
+		}
+		ports = append(ports, port)
+	}
+	nat.Sort(ports, func(i, j nat.Port) bool {
+		return i.Int() < j.Int() || (i.Int() == j.Int() && i.Proto() < j.Proto())
+	})
+	return ports
 }
 
-// Containers2Compose 将容器详细信息打印为 docker-compose 格式
-func Containers2Compose(containersJSON []types.ContainerJSON) map[string]string {
+// Containers2Compose 将容器详细信息打印为 docker-compose 格式，每个容器一个文件，
+// 容器之间的引用使用 container:<name> 的形式
+func Containers2Compose(containersJSON []types.ContainerJSON, opts *ExportOptions) (map[string]string, error) {
+	graph, err := buildContainerGraph(containersJSON)
+	if err != nil {
+		return nil, err
+	}
 	services := make(map[string]string)
 
 	for _, containerJSON := range containersJSON {
 		var compose strings.Builder
 		compose.WriteString("version: '3'\n")
 		compose.WriteString("services:\n")
-		compose.WriteString(generateServiceConfig(containerJSON))
+		compose.WriteString(generateServiceConfig(containerJSON, graph, false, false, opts.envFiles[strings.TrimPrefix(containerJSON.Name, "/")], opts.rawEnvFiles))
+		compose.WriteString(generateComposeTopLevel([]types.ContainerJSON{containerJSON}, opts))
 
 		cname := strings.TrimPrefix(containerJSON.Name, "/")
 		services[cname] = compose.String()
 	}
 
-	return services
+	return services, nil
+}
+
+// Containers2ComposeFile 将所有容器写入同一个 docker-compose 文件，并生成顶级 networks 和 volumes，
+// 容器之间的引用转换为 depends_on 和 service:<name> 的形式。
+// opts.Swarm 为 true 时生成用于 docker stack deploy 的文件
+func Containers2ComposeFile(containersJSON []types.ContainerJSON, opts *ExportOptions) (string, error) {
+	graph, err := buildContainerGraph(containersJSON)
+	if err != nil {
+		return "", err
+	}
+
+	var compose strings.Builder
+	if opts.Swarm {
+		// deploy 中的部分配置需要 3.8 版本的格式
+		compose.WriteString("version: '3.8'\n")
+	} else {
+		compose.WriteString("version: '3'\n")
+	}
+	compose.WriteString("services:\n")
+	for _, containerJSON := range graph.sortContainers(containersJSON) {
+		compose.WriteString(generateServiceConfig(containerJSON, graph, true, opts.Swarm, opts.envFiles[strings.TrimPrefix(containerJSON.Name, "/")], opts.rawEnvFiles))
+	}
+	compose.WriteString(generateComposeTopLevel(containersJSON, opts))
+	return compose.String(), nil
+}
+
+// generateComposeTopLevel 生成容器用到的自定义网络和命名卷的顶级定义
+func generateComposeTopLevel(containersJSON []types.ContainerJSON, opts *ExportOptions) string {
+	var topLevel strings.Builder
+
+	if networks := referencedNetworks(containersJSON); len(networks) > 0 {
+		topLevel.WriteString("networks:\n")
+		for _, name := range networks {
+			topLevel.WriteString(fmt.Sprintf("  %s:\n", composeScalar(name)))
+			// 指定 name 避免 compose 为网络加上项目名前缀
+			topLevel.WriteString(fmt.Sprintf("    name: %s\n", composeScalar(name)))
+			networkJSON, ok := opts.Networks[name]
+			if opts.External || !ok {
+				topLevel.WriteString("    external: true\n")
+				continue
+			}
+			driver := networkJSON.Driver
+			if opts.Swarm && driver == "bridge" {
+				// 单机的 bridge 网络在 swarm 中对应 overlay 网络
+				driver = "overlay"
+			}
+			topLevel.WriteString(fmt.Sprintf("    driver: %s\n", composeScalar(driver)))
+			if driver == networkJSON.Driver {
+				writeComposeMap(&topLevel, "    driver_opts", networkJSON.Options)
+			}
+			if networkJSON.Internal {
+				topLevel.WriteString("    internal: true\n")
+			}
+			if networkJSON.Attachable {
+				topLevel.WriteString("    attachable: true\n")
+			}
+			if networkJSON.EnableIPv6 {
+				topLevel.WriteString("    enable_ipv6: true\n")
+			}
+			topLevel.WriteString(generateComposeIPAM(networkJSON.IPAM))
+			writeComposeMap(&topLevel, "    labels", userLabels(networkJSON.Labels))
+		}
+	}
+
+	if volumes := referencedVolumes(containersJSON); len(volumes) > 0 {
+		topLevel.WriteString("volumes:\n")
+		for _, mount := range volumes {
+			topLevel.WriteString(fmt.Sprintf("  %s:\n", composeScalar(mount.Name)))
+			topLevel.WriteString(fmt.Sprintf("    name: %s\n", composeScalar(mount.Name)))
+			if opts.External {
+				topLevel.WriteString("    external: true\n")
+				continue
+			}
+			volumeJSON := volumeDefinition(mount, opts.Volumes)
+			if volumeJSON.Driver != "" {
+				topLevel.WriteString(fmt.Sprintf("    driver: %s\n", composeScalar(volumeJSON.Driver)))
+			}
+			writeComposeMap(&topLevel, "    driver_opts", volumeJSON.Options)
+			writeComposeMap(&topLevel, "    labels", userLabels(volumeJSON.Labels))
+		}
+	}
+
+	return topLevel.String()
+}
+
+// writeComposeMap 按照键排序写入 compose 中的映射，空映射不输出
+func writeComposeMap(builder *strings.Builder, key string, m map[string]string) {
+	if len(m) == 0 {
+		return
+	}
+	indent := strings.Repeat(" ", len(key)-len(strings.TrimLeft(key, " ")))
+	builder.WriteString(key + ":\n")
+	for _, k := range sortedKeys(m) {
+		builder.WriteString(fmt.Sprintf("%s  %s: %s\n", indent, composeScalar(k), composeScalar(m[k])))
+	}
+}
+
+// sortedKeys 返回排序后的映射键
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isBuiltinNetwork 判断是否为 docker 内置网络
+func isBuiltinNetwork(name string) bool {
+	switch name {
+	case "", "default", "bridge", "host", "none":
+		return true
+	}
+	return false
+}
+
+// userNetworks 返回容器连接的自定义网络，按名称排序
+func userNetworks(containerJSON types.ContainerJSON) []string {
+	var networks []string
+	if containerJSON.NetworkSettings == nil {
+		return networks
+	}
+	for name := range containerJSON.NetworkSettings.Networks {
+		if !isBuiltinNetwork(name) {
+			networks = append(networks, name)
+		}
+	}
+	sort.Strings(networks)
+	return networks
+}
+
+// anonymousVolumeRegexp 匿名卷的名称为 64 位十六进制字符串
+var anonymousVolumeRegexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// isAnonymousVolume 判断是否为匿名卷
+func isAnonymousVolume(name string) bool {
+	return anonymousVolumeRegexp.MatchString(name)
+}
+
+// namedVolumes 返回容器挂载的命名卷
+func namedVolumes(containerJSON types.ContainerJSON) []types.MountPoint {
+	var volumes []types.MountPoint
+	for _, mount := range containerJSON.Mounts {
+		if mount.Type == "volume" && mount.Name != "" && !isAnonymousVolume(mount.Name) {
+			volumes = append(volumes, mount)
+		}
+	}
+	return volumes
 }
 
-// generateServiceConfig 生成单个服务的配置
-func generateServiceConfig(containerJSON types.ContainerJSON) string {
+// generateServiceConfig 生成单个服务的配置，sameFile 为 true 时被引用的容器位于同一个 compose 文件中，
+// swarm 为 true 时生成 deploy 配置，并去掉 swarm 服务不支持的配置，envFile 不为空时通过 env_file 引用环境变量文件，
+// rawEnvFiles 中的文件需要按照 raw 格式读取
+func generateServiceConfig(containerJSON types.ContainerJSON, graph *containerGraph, sameFile, swarm bool, envFile string, rawEnvFiles map[string]bool) string {
 	var serviceConfig strings.Builder
 	cname := strings.TrimPrefix(containerJSON.Name, "/")
 
 	// Service name
-	serviceConfig.WriteString(fmt.Sprintf("%s:\n", cname))
+	serviceConfig.WriteString(fmt.Sprintf("  %s:\n", cname))
 
 	// image
-	serviceConfig.WriteString(fmt.Sprintf("  image: %s\n", containerJSON.Config.Image))
+	serviceConfig.WriteString(fmt.Sprintf("    image: %s\n", composeScalar(containerJSON.Config.Image)))
 
 	// command
 	if len(containerJSON.Config.Cmd) > 0 {
-		serviceConfig.WriteString(fmt.Sprintf("  command: [%s]\n", strings.Join(containerJSON.Config.Cmd, ", ")))
+		serviceConfig.WriteString(fmt.Sprintf("    command: %s\n", composeFlowList(containerJSON.Config.Cmd)))
 	}
 
 	// environment
+	if envFile != "" {
+		serviceConfig.WriteString("    env_file:\n")
+		if !rawEnvFiles[envFile] {
+			// 每一行按照 .env 的规则读取时结果不变，使用所有版本都支持的简写形式，swarm 中总是如此
+			serviceConfig.WriteString(fmt.Sprintf("      - %s\n", composeScalar("./"+envFile)))
+		} else {
+			// raw 格式与 --env-file 一致，不处理引号和变量，需要 docker compose 2.30 及以上版本
+			serviceConfig.WriteString(fmt.Sprintf("      - path: %s\n", composeScalar("./"+envFile)))
+			serviceConfig.WriteString("        format: raw\n")
+		}
+	}
 	if len(containerJSON.Config.Env) > 0 {
-		serviceConfig.WriteString("  environment:\n")
+		serviceConfig.WriteString("    environment:\n")
 		for _, env := range containerJSON.Config.Env {
-			serviceConfig.WriteString(fmt.Sprintf("    - %s\n", env))
+			serviceConfig.WriteString(fmt.Sprintf("      - %s\n", composeScalar(env)))
 		}
 	}
 
 	// workdir
 	if containerJSON.Config.WorkingDir != "" {
-		serviceConfig.WriteString(fmt.Sprintf("  working_dir: %s\n", containerJSON.Config.WorkingDir))
+		serviceConfig.WriteString(fmt.Sprintf("    working_dir: %s\n", composeScalar(containerJSON.Config.WorkingDir)))
 	}
 
 	// entrypoint
 	if len(containerJSON.Config.Entrypoint) > 0 {
-		serviceConfig.WriteString(fmt.Sprintf("  entrypoint: [%s]\n", strings.Join(containerJSON.Config.Entrypoint, ", ")))
+		serviceConfig.WriteString(fmt.Sprintf("    entrypoint: %s\n", composeFlowList(containerJSON.Config.Entrypoint)))
 	}
 
 	// hostname
-	if containerJSON.Config.Hostname != "" {
-		serviceConfig.WriteString(fmt.Sprintf("  hostname: %s\n", containerJSON.Config.Hostname))
+	if hostname := customHostname(containerJSON); hostname != "" {
+		serviceConfig.WriteString(fmt.Sprintf("    hostname: %s\n", composeScalar(hostname)))
 	}
 
 	// user
 	if containerJSON.Config.User != "" {
-		serviceConfig.WriteString(fmt.Sprintf("  user: %s\n", containerJSON.Config.User))
+		serviceConfig.WriteString(fmt.Sprintf("    user: %s\n", composeScalar(containerJSON.Config.User)))
+	}
+
+	// healthcheck
+	if healthcheck := containerJSON.Config.Healthcheck; healthcheck != nil {
+		serviceConfig.WriteString("    healthcheck:\n")
+		switch {
+		case len(healthcheck.Test) > 0 && healthcheck.Test[0] == "NONE":
+			serviceConfig.WriteString("      disable: true\n")
+		case len(healthcheck.Test) > 0:
+			serviceConfig.WriteString(fmt.Sprintf("      test: %s\n", composeFlowList(healthcheck.Test)))
+		}
+		if healthcheck.Interval > 0 {
+			serviceConfig.WriteString(fmt.Sprintf("      interval: %s\n", healthcheck.Interval))
+		}
+		if healthcheck.Timeout > 0 {
+			serviceConfig.WriteString(fmt.Sprintf("      timeout: %s\n", healthcheck.Timeout))
+		}
+		if healthcheck.StartPeriod > 0 {
+			serviceConfig.WriteString(fmt.Sprintf("      start_period: %s\n", healthcheck.StartPeriod))
+		}
+		if healthcheck.Retries > 0 {
+			serviceConfig.WriteString(fmt.Sprintf("      retries: %d\n", healthcheck.Retries))
+		}
+	}
+
+	// init
+	if containerJSON.HostConfig.Init != nil && *containerJSON.HostConfig.Init {
+		serviceConfig.WriteString("    init: true\n")
+	}
+
+	// stop signal and timeout
+	if containerJSON.Config.StopSignal != "" {
+		serviceConfig.WriteString(fmt.Sprintf("    stop_signal: %s\n", composeScalar(containerJSON.Config.StopSignal)))
+	}
+	if containerJSON.Config.StopTimeout != nil {
+		serviceConfig.WriteString(fmt.Sprintf("    stop_grace_period: %ds\n", *containerJSON.Config.StopTimeout))
 	}
 
 	// Privileged mode
 	if containerJSON.HostConfig.Privileged {
-		serviceConfig.WriteString("  privileged: true\n")
+		if swarm {
+			ezap.Warnf("%s: privileged is not supported by swarm services, dropped", cname)
+		} else {
+			serviceConfig.WriteString("    privileged: true\n")
+		}
 	}
 
-	// restart policy
-	if containerJSON.HostConfig.RestartPolicy.Name != "" && containerJSON.HostConfig.RestartPolicy.Name != "no" {
-		serviceConfig.WriteString(fmt.Sprintf("  restart: %s\n", containerJSON.HostConfig.RestartPolicy.Name))
+	// devices
+	if swarm && len(containerJSON.HostConfig.Devices) > 0 {
+		ezap.Warnf("%s: devices are not supported by swarm services, dropped", cname)
+	}
+
+	// restart policy，swarm 中使用 deploy.restart_policy
+	if !swarm && containerJSON.HostConfig.RestartPolicy.Name != "" && containerJSON.HostConfig.RestartPolicy.Name != "no" {
+		restart := string(containerJSON.HostConfig.RestartPolicy.Name)
+		if containerJSON.HostConfig.RestartPolicy.MaximumRetryCount > 0 {
+			restart += ":" + strconv.Itoa(containerJSON.HostConfig.RestartPolicy.MaximumRetryCount)
+		}
+		serviceConfig.WriteString(fmt.Sprintf("    restart: %s\n", restart))
 	}
 
 	// port mapping
 	if len(containerJSON.NetworkSettings.Ports) > 0 {
-		serviceConfig.WriteString("  ports:\n")
-		for port, bindings := range containerJSON.NetworkSettings.Ports {
-			for _, binding := range bindings {
-				if binding.HostIP == "0.0.0.0" || binding.HostIP == "" || binding.HostIP == "::" {
-					serviceConfig.WriteString(fmt.Sprintf("    - \"%s:%s\"\n", binding.HostPort, port.Port()))
-				} else {
-					serviceConfig.WriteString(fmt.Sprintf("    - \"%s:%s:%s\"\n", binding.HostIP, binding.HostPort, port.Port()))
+		serviceConfig.WriteString("    ports:\n")
+		addedPorts := make(map[string]bool)
+		for _, port := range containerPorts(containerJSON) {
+			for _, binding := range containerJSON.NetworkSettings.Ports[port] {
+				if swarm && binding.HostIP != "" && binding.HostIP != "0.0.0.0" && binding.HostIP != "::" {
+					// swarm 通过 ingress 网络在所有节点上发布端口
+					ezap.Warnf("%s: host ip %s of port %s is not supported by swarm services, dropped", cname, binding.HostIP, binding.HostPort)
+					binding.HostIP = ""
+				}
+				portMapping := portSpec(port, binding)
+				if !addedPorts[portMapping] {
+					addedPorts[portMapping] = true
+					serviceConfig.WriteString(fmt.Sprintf("      - \"%s\"\n", portMapping))
 				}
 			}
 		}
 	}
 
+	// exposed ports without a published binding
+	if ports := exposedOnlyPorts(containerJSON); len(ports) > 0 {
+		serviceConfig.WriteString("    expose:\n")
+		for _, port := range ports {
+			serviceConfig.WriteString(fmt.Sprintf("      - %s\n", yamlQuote(string(port))))
+		}
+	}
+
 	// volume
 	if len(containerJSON.Mounts) > 0 {
-		serviceConfig.WriteString("  volumes:\n")
+		serviceConfig.WriteString("    volumes:\n")
 		for _, mount := range containerJSON.Mounts {
-			if mount.Type == "bind" {
-				serviceConfig.WriteString(fmt.Sprintf("    - \"%s:%s\"\n", mount.Source, mount.Destination))
-			} else {
-				serviceConfig.WriteString(fmt.Sprintf("    - \"%s:%s\"\n", mount.Source, mount.Destination)) // Volume mounts can also be handled here
+			m := hostMount(containerJSON, mount.Destination)
+			switch {
+			case needsLongSyntax(m):
+				serviceConfig.WriteString(generateComposeMount(*m))
+			case mount.Type == "bind" || mount.Type == "volume":
+				// 命名卷引用顶级 volumes 中的定义，匿名卷只指定容器内路径
+				serviceConfig.WriteString(fmt.Sprintf("      - %s\n", composeQuote(shortMountSpec(mount))))
+			default:
+				serviceConfig.WriteString(fmt.Sprintf("      - %s\n", composeQuote(mount.Source+":"+mount.Destination)))
 			}
 		}
 	}
 
 	// capabilities
 	if len(containerJSON.HostConfig.CapAdd) > 0 {
-		serviceConfig.WriteString("  cap_add:\n")
+		serviceConfig.WriteString("    cap_add:\n")
 		for _, cap := range containerJSON.HostConfig.CapAdd {
-			serviceConfig.WriteString(fmt.Sprintf("    - %s\n", cap))
+			serviceConfig.WriteString(fmt.Sprintf("      - %s\n", cap))
 		}
 	}
 	if len(containerJSON.HostConfig.CapDrop) > 0 {
-		serviceConfig.WriteString("  cap_drop:\n")
+		serviceConfig.WriteString("    cap_drop:\n")
 		for _, cap := range containerJSON.HostConfig.CapDrop {
-			serviceConfig.WriteString(fmt.Sprintf("    - %s\n", cap))
+			serviceConfig.WriteString(fmt.Sprintf("      - %s\n", cap))
+		}
+	}
+
+	// security options
+	if securityOpt := securityOptions(containerJSON.HostConfig); len(securityOpt) > 0 {
+		if swarm {
+			ezap.Warnf("%s: security_opt is not supported by swarm services, dropped", cname)
+		} else {
+			serviceConfig.WriteString("    security_opt:\n")
+			for _, opt := range securityOpt {
+				serviceConfig.WriteString(fmt.Sprintf("      - %s\n", composeQuote(opt)))
+			}
+		}
+	}
+
+	// supplementary groups
+	if len(containerJSON.HostConfig.GroupAdd) > 0 {
+		if swarm {
+			ezap.Warnf("%s: group_add is not supported by swarm services, dropped", cname)
+		} else {
+			serviceConfig.WriteString("    group_add:\n")
+			for _, group := range containerJSON.HostConfig.GroupAdd {
+				serviceConfig.WriteString(fmt.Sprintf("      - %s\n", yamlQuote(group)))
+			}
 		}
 	}
 
 	// OOM score adjustment
 	if containerJSON.HostConfig.OomScoreAdj != 0 {
-		serviceConfig.WriteString(fmt.Sprintf("  oom_score_adj: %d\n", containerJSON.HostConfig.OomScoreAdj))
+		serviceConfig.WriteString(fmt.Sprintf("    oom_score_adj: %d\n", containerJSON.HostConfig.OomScoreAdj))
+	}
+
+	// uts and cgroup namespace mode
+	if containerJSON.HostConfig.UTSMode.IsHost() {
+		if swarm {
+			ezap.Warnf("%s: uts is not supported by swarm services, dropped", cname)
+		} else {
+			serviceConfig.WriteString("    uts: host\n")
+		}
+	}
+	if cgroupnsMode := containerJSON.HostConfig.CgroupnsMode; cgroupnsMode != "" {
+		if swarm {
+			ezap.Warnf("%s: cgroup is not supported by swarm services, dropped", cname)
+		} else {
+			serviceConfig.WriteString(fmt.Sprintf("    cgroup: %s\n", cgroupnsMode))
+		}
 	}
 
 	// User namespace mode
 	if containerJSON.HostConfig.UsernsMode != "" {
-		serviceConfig.WriteString(fmt.Sprintf("  userns_mode: %s\n", containerJSON.HostConfig.UsernsMode))
+		if swarm {
+			ezap.Warnf("%s: userns_mode is not supported by swarm services, dropped", cname)
+		} else {
+			serviceConfig.WriteString(fmt.Sprintf("    userns_mode: %s\n", containerJSON.HostConfig.UsernsMode))
+		}
+	}
+
+	// resources，swarm 中 CPU 和内存限制写在 deploy.resources 中
+	if swarm {
+		resources := *containerJSON.HostConfig
+		resources.NanoCPUs, resources.Memory, resources.MemoryReservation = 0, 0, 0
+		for _, option := range resourceOptions(&resources) {
+			ezap.Warnf("%s: %s is not supported by swarm services, dropped", cname, option[0])
+		}
+	} else {
+		serviceConfig.WriteString(generateComposeResources(cname, containerJSON.HostConfig))
+	}
+
+	// OOM killer, shared memory size and storage options
+	if oomKillDisable := containerJSON.HostConfig.OomKillDisable; oomKillDisable != nil && *oomKillDisable {
+		if swarm {
+			ezap.Warnf("%s: oom_kill_disable is not supported by swarm services, dropped", cname)
+		} else {
+			serviceConfig.WriteString("    oom_kill_disable: true\n")
+		}
+	}
+	if shmSize := containerJSON.HostConfig.ShmSize; shmSize > 0 && shmSize != defaultShmSize {
+		if swarm {
+			ezap.Warnf("%s: shm_size is not supported by swarm services, mount a tmpfs at /dev/shm instead", cname)
+		} else {
+			serviceConfig.WriteString(fmt.Sprintf("    shm_size: %s\n", bytesSize(shmSize)))
+		}
+	}
+	if len(containerJSON.HostConfig.StorageOpt) > 0 {
+		if swarm {
+			ezap.Warnf("%s: storage_opt is not supported by swarm services, dropped", cname)
+		} else {
+			writeComposeMap(&serviceConfig, "    storage_opt", containerJSON.HostConfig.StorageOpt)
+		}
+	}
+
+	// ulimits，软限制和硬限制相同时使用单个值
+	if len(containerJSON.HostConfig.Ulimits) > 0 {
+		serviceConfig.WriteString("    ulimits:\n")
+		for _, ulimit := range containerJSON.HostConfig.Ulimits {
+			if ulimit.Soft == ulimit.Hard {
+				serviceConfig.WriteString(fmt.Sprintf("      %s: %d\n", ulimit.Name, ulimit.Soft))
+				continue
+			}
+			serviceConfig.WriteString(fmt.Sprintf("      %s:\n", ulimit.Name))
+			serviceConfig.WriteString(fmt.Sprintf("        soft: %d\n", ulimit.Soft))
+			serviceConfig.WriteString(fmt.Sprintf("        hard: %d\n", ulimit.Hard))
+		}
+	}
+
+	// sysctls
+	writeComposeMap(&serviceConfig, "    sysctls", containerJSON.HostConfig.Sysctls)
+
+	// tmpfs
+	if len(containerJSON.HostConfig.Tmpfs) > 0 {
+		serviceConfig.WriteString("    tmpfs:\n")
+		for _, path := range sortedKeys(containerJSON.HostConfig.Tmpfs) {
+			serviceConfig.WriteString(fmt.Sprintf("      - %s\n", composeQuote(tmpfsSpec(path, containerJSON.HostConfig.Tmpfs[path]))))
+		}
+	}
+
+	// swarm 服务的任务可能调度到不同节点，不能引用其他容器
+	if swarm {
+		for _, ref := range graph.refs[cname] {
+			ezap.Warnf("%s: %s reference to container %s is not supported by swarm services, dropped", cname, ref.Kind, ref.Target)
+		}
+		if networkMode := containerJSON.HostConfig.NetworkMode; networkMode.IsHost() || networkMode.IsNone() {
+			ezap.Warnf("%s: network_mode %s is not supported by swarm services, attach the service to the %s network instead", cname, networkMode, networkMode)
+		}
+		if networks := userNetworks(containerJSON); len(networks) > 0 {
+			serviceConfig.WriteString("    networks:\n")
+			for _, name := range networks {
+				serviceConfig.WriteString(fmt.Sprintf("      - %s\n", composeScalar(name)))
+			}
+		}
+		serviceConfig.WriteString(generateDeployConfig(containerJSON))
+		return serviceConfig.String()
+	}
+
+	// 引用其他容器时，同一文件中使用 service:<name>，否则使用 container:<name>
+	refPrefix := "container:"
+	if sameFile {
+		refPrefix = "service:"
 	}
 
 	// IPC mode
-	if containerJSON.HostConfig.IpcMode != "" {
-		serviceConfig.WriteString(fmt.Sprintf("  ipc: %s\n", containerJSON.HostConfig.IpcMode))
+	if ref, ok := graph.ref(cname, refIpc); ok {
+		serviceConfig.WriteString(fmt.Sprintf("    ipc: %s\n", composeScalar(refPrefix+ref.Target)))
+	} else if containerJSON.HostConfig.IpcMode != "" {
+		serviceConfig.WriteString(fmt.Sprintf("    ipc: %s\n", containerJSON.HostConfig.IpcMode))
+	}
+
+	// PID mode
+	if ref, ok := graph.ref(cname, refPid); ok {
+		serviceConfig.WriteString(fmt.Sprintf("    pid: %s\n", composeScalar(refPrefix+ref.Target)))
+	} else if containerJSON.HostConfig.PidMode != "" {
+		serviceConfig.WriteString(fmt.Sprintf("    pid: %s\n", composeScalar(string(containerJSON.HostConfig.PidMode))))
+	}
+
+	// links
+	if links := graph.refsOf(cname, refLink); len(links) > 0 {
+		if sameFile {
+			serviceConfig.WriteString("    links:\n")
+		} else {
+			serviceConfig.WriteString("    external_links:\n")
+		}
+		for _, ref := range links {
+			link := ref.Target
+			if ref.Option != "" {
+				link += ":" + ref.Option
+			}
+			serviceConfig.WriteString(fmt.Sprintf("      - %s\n", composeScalar(link)))
+		}
+	}
+
+	// volumes from
+	if volumesFrom := graph.refsOf(cname, refVolumesFrom); len(volumesFrom) > 0 {
+		serviceConfig.WriteString("    volumes_from:\n")
+		for _, ref := range volumesFrom {
+			from := ref.Target
+			if !sameFile {
+				from = "container:" + from
+			}
+			if ref.Option != "" {
+				from += ":" + ref.Option
+			}
+			serviceConfig.WriteString(fmt.Sprintf("      - %s\n", composeScalar(from)))
+		}
+	}
+
+	// depends on
+	if deps := graph.dependencies(cname); sameFile && len(deps) > 0 {
+		serviceConfig.WriteString("    depends_on:\n")
+		for _, dep := range deps {
+			serviceConfig.WriteString(fmt.Sprintf("      - %s\n", composeScalar(dep)))
+		}
+	}
+
+	// network mode
+	networkMode := string(containerJSON.HostConfig.NetworkMode)
+	if ref, ok := graph.ref(cname, refNetwork); ok {
+		serviceConfig.WriteString(fmt.Sprintf("    network_mode: %s\n", composeScalar(refPrefix+ref.Target)))
+	} else if networkMode == "host" || networkMode == "none" {
+		serviceConfig.WriteString(fmt.Sprintf("    network_mode: %s\n", composeScalar(networkMode)))
+	} else if networks := userNetworks(containerJSON); len(networks) > 0 {
+		serviceConfig.WriteString(generateComposeNetworks(containerJSON, networks))
 	}
 
 	return serviceConfig.String()
 }
+
+// generateComposeNetworks 生成服务的 networks 配置，有别名、固定 IP 或 MAC 地址时使用映射的形式
+func generateComposeNetworks(containerJSON types.ContainerJSON, networks []string) string {
+	var config strings.Builder
+	cname := strings.TrimPrefix(containerJSON.Name, "/")
+
+	settings := make(map[string]string)
+	for _, name := range networks {
+		endpoint := networkEndpoint(containerJSON, name)
+		if endpoint == nil {
+			continue
+		}
+		var setting strings.Builder
+		if aliases := endpointAliases(containerJSON, endpoint); len(aliases) > 0 {
+			setting.WriteString("        aliases:\n")
+			for _, alias := range aliases {
+				setting.WriteString(fmt.Sprintf("          - %s\n", composeScalar(alias)))
+			}
+		}
+		if ipam := endpoint.IPAMConfig; ipam != nil {
+			if ipam.IPv4Address != "" {
+				setting.WriteString(fmt.Sprintf("        ipv4_address: %s\n", ipam.IPv4Address))
+			}
+			if ipam.IPv6Address != "" {
+				setting.WriteString(fmt.Sprintf("        ipv6_address: %s\n", yamlQuote(ipam.IPv6Address)))
+			}
+		}
+		if mac := customMacAddress(endpoint); mac != "" {
+			setting.WriteString(fmt.Sprintf("        mac_address: %s\n", yamlQuote(mac)))
+		}
+		if len(endpoint.Links) > 0 {
+			ezap.Warnf("%s: links on network %s are not supported by compose, use network aliases instead", cname, name)
+		}
+		if setting.Len() > 0 {
+			settings[name] = setting.String()
+		}
+	}
+
+	config.WriteString("    networks:\n")
+	for _, name := range networks {
+		setting, ok := settings[name]
+		switch {
+		case len(settings) == 0:
+			config.WriteString(fmt.Sprintf("      - %s\n", composeScalar(name)))
+		case ok:
+			config.WriteString(fmt.Sprintf("      %s:\n", composeScalar(name)))
+			config.WriteString(setting)
+		default:
+			config.WriteString(fmt.Sprintf("      %s: {}\n", composeScalar(name)))
+		}
+	}
+	return config.String()
+}
+
+// generateComposeIPAM 生成顶级网络的 ipam 配置，没有自定义配置时返回空字符串
+func generateComposeIPAM(ipam network.IPAM) string {
+	var config strings.Builder
+	if ipam.Driver != "" && ipam.Driver != "default" {
+		config.WriteString(fmt.Sprintf("      driver: %s\n", composeScalar(ipam.Driver)))
+	}
+	if len(ipam.Config) > 0 {
+		config.WriteString("      config:\n")
+		for _, pool := range ipam.Config {
+			var fields []string
+			if pool.Subnet != "" {
+				fields = append(fields, "subnet: "+yamlQuote(pool.Subnet))
+			}
+			if pool.IPRange != "" {
+				fields = append(fields, "ip_range: "+yamlQuote(pool.IPRange))
+			}
+			if pool.Gateway != "" {
+				fields = append(fields, "gateway: "+yamlQuote(pool.Gateway))
+			}
+			if len(pool.AuxAddress) > 0 {
+				var aux strings.Builder
+				aux.WriteString("aux_addresses:\n")
+				for _, host := range sortedKeys(pool.AuxAddress) {
+					aux.WriteString(fmt.Sprintf("            %s: %s\n", composeScalar(host), yamlQuote(pool.AuxAddress[host])))
+				}
+				fields = append(fields, strings.TrimSuffix(aux.String(), "\n"))
+			}
+			if len(fields) == 0 {
+				continue
+			}
+			config.WriteString("        - " + fields[0] + "\n")
+			for _, field := range fields[1:] {
+				config.WriteString("          " + field + "\n")
+			}
+		}
+	}
+	writeComposeMap(&config, "      options", ipam.Options)
+	if config.Len() == 0 {
+		return ""
+	}
+	return "    ipam:\n" + config.String()
+}
+
+// generateDeployConfig 生成 swarm 服务的 deploy 配置，包括重启策略、资源限制和服务标签
+func generateDeployConfig(containerJSON types.ContainerJSON) string {
+	var deploy strings.Builder
+	hostConfig := containerJSON.HostConfig
+
+	deploy.WriteString("    deploy:\n")
+	deploy.WriteString("      replicas: 1\n")
+
+	// restart policy
+	condition := "any"
+	switch hostConfig.RestartPolicy.Name {
+	case container.RestartPolicyDisabled, "":
+		condition = "none"
+	case container.RestartPolicyOnFailure:
+		condition = "on-failure"
+	}
+	deploy.WriteString("      restart_policy:\n")
+	deploy.WriteString(fmt.Sprintf("        condition: %s\n", condition))
+	if hostConfig.RestartPolicy.MaximumRetryCount > 0 {
+		deploy.WriteString(fmt.Sprintf("        max_attempts: %d\n", hostConfig.RestartPolicy.MaximumRetryCount))
+	}
+
+	// resources
+	if hostConfig.NanoCPUs > 0 || hostConfig.Memory > 0 || hostConfig.MemoryReservation > 0 {
+		deploy.WriteString("      resources:\n")
+		if hostConfig.NanoCPUs > 0 || hostConfig.Memory > 0 {
+			deploy.WriteString("        limits:\n")
+			if hostConfig.NanoCPUs > 0 {
+				deploy.WriteString(fmt.Sprintf("          cpus: '%s'\n", strconv.FormatFloat(float64(hostConfig.NanoCPUs)/1e9, 'f', -1, 64)))
+			}
+			if hostConfig.Memory > 0 {
+				deploy.WriteString(fmt.Sprintf("          memory: %s\n", bytesSize(hostConfig.Memory)))
+			}
+		}
+		if hostConfig.MemoryReservation > 0 {
+			deploy.WriteString("        reservations:\n")
+			deploy.WriteString(fmt.Sprintf("          memory: %s\n", bytesSize(hostConfig.MemoryReservation)))
+		}
+	}
+
+	// labels
+	writeComposeMap(&deploy, "      labels", containerJSON.Config.Labels)
+
+	return deploy.String()
+}
+
+// bytesSize 将字节数转换为 docker 接受的容量表示，能整除时使用 g、m、k 单位
+func bytesSize(bytes int64) string {
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"g", 1 << 30}, {"m", 1 << 20}, {"k", 1 << 10}} {
+		if bytes%unit.size == 0 {
+			return strconv.FormatInt(bytes/unit.size, 10) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(bytes, 10)
+}
+
+// mountOptions 拼接 --mount 的参数，docker 按照 CSV 解析，包含逗号或引号的字段需要加引号
+func mountOptions(fields ...string) string {
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		if strings.ContainsAny(field, ",\"\n") {
+			field = `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+		}
+		quoted[i] = field
+	}
+	return strings.Join(quoted, ",")
+}
@@ -0,0 +1,39 @@
+package dockercli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestCustomHostname(t *testing.T) {
+	formats := []string{"command", "compose", "kubernetes", "quadlet", "systemd", "nomad", "ansible", "terraform"}
+	tests := []struct {
+		name     string
+		id       string
+		hostname string
+		want     bool
+	}{
+		{"default", "aaaaaaaaaaaa1111", "aaaaaaaaaaaa", false},
+		{"custom", "aaaaaaaaaaaa1111", "web.local", true},
+		// 部分 inspect 结果中的 ID 可能不足 12 位
+		{"short id", "abc", "abc", false},
+		{"short id with custom hostname", "abc", "web.local", true},
+	}
+	for _, tt := range tests {
+		for _, format := range formats {
+			t.Run(tt.name+"/"+format, func(t *testing.T) {
+				web := testContainer("aaaaaaaaaaaa1111", "web")
+				web.ID, web.Config.Hostname = tt.id, tt.hostname
+				out := exportString(t, []types.ContainerJSON{web}, &ExportOptions{Format: format})
+				if got := strings.Contains(out, "web.local"); got != tt.want {
+					t.Errorf("hostname exported = %v, want %v:\n%s", got, tt.want, out)
+				}
+				if !tt.want && strings.Contains(strings.ToLower(out), "hostname") {
+					t.Errorf("unexpected hostname:\n%s", out)
+				}
+			})
+		}
+	}
+}
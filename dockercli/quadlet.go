@@ -0,0 +1,378 @@
+package dockercli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// Containers2Quadlet 为每个容器生成 Podman Quadlet 的 .container 文件，
+// 自定义网络和命名卷生成 .network、.volume 文件，opts.External 为 true 时引用已存在的网络和卷
+func Containers2Quadlet(containersJSON []types.ContainerJSON, opts *ExportOptions) (Files, error) {
+	graph, err := buildContainerGraph(containersJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(Files)
+	for _, containerJSON := range containersJSON {
+		cname := strings.TrimPrefix(containerJSON.Name, "/")
+		files[cname+".container"] = generateQuadletContainer(containerJSON, graph, opts)
+
+		if opts.External {
+			continue
+		}
+		for _, name := range userNetworks(containerJSON) {
+			files[name+".network"] = generateQuadletNetwork(name, opts)
+		}
+		for _, mount := range namedVolumes(containerJSON) {
+			files[mount.Name+".volume"] = generateQuadletVolume(volumeDefinition(mount, opts.Volumes))
+		}
+	}
+	return files, nil
+}
+
+// generateQuadletContainer 生成单个容器的 .container 文件
+func generateQuadletContainer(containerJSON types.ContainerJSON, graph *containerGraph, opts *ExportOptions) string {
+	var unit strings.Builder
+	cname := strings.TrimPrefix(containerJSON.Name, "/")
+	config := containerJSON.Config
+	hostConfig := containerJSON.HostConfig
+
+	// [Unit]
+	unit.WriteString("[Unit]\n")
+	description := config.Labels["description"]
+	if description == "" {
+		description = "Container " + cname
+	}
+	unit.WriteString(fmt.Sprintf("Description=%s\n", systemdComment(description)))
+	// quadlet 生成的服务名称与 .container 文件名相同
+	for _, dep := range graph.dependencies(cname) {
+		unit.WriteString(fmt.Sprintf("Requires=%s.service\n", dep))
+		unit.WriteString(fmt.Sprintf("After=%s.service\n", dep))
+	}
+
+	// [Container]
+	unit.WriteString("\n[Container]\n")
+	unit.WriteString(fmt.Sprintf("ContainerName=%s\n", cname))
+	unit.WriteString(fmt.Sprintf("Image=%s\n", qualifiedImage(config.Image)))
+
+	// hostname
+	if hostname := customHostname(containerJSON); hostname != "" {
+		unit.WriteString(fmt.Sprintf("HostName=%s\n", systemdQuote(hostname)))
+	}
+
+	// user
+	if config.User != "" {
+		user, group, found := strings.Cut(config.User, ":")
+		unit.WriteString(fmt.Sprintf("User=%s\n", systemdQuote(user)))
+		if found {
+			unit.WriteString(fmt.Sprintf("Group=%s\n", systemdQuote(group)))
+		}
+	}
+
+	// workdir
+	if config.WorkingDir != "" {
+		unit.WriteString(fmt.Sprintf("WorkingDir=%s\n", systemdQuote(config.WorkingDir)))
+	}
+
+	// entrypoint
+	// 与 docker run 相同，多个元素的 entrypoint 只保留第一个，其余放到 Exec 前面
+	var entrypointArgs []string
+	if len(config.Entrypoint) > 0 {
+		unit.WriteString(fmt.Sprintf("Entrypoint=%s\n", systemdQuote(config.Entrypoint[0])))
+		entrypointArgs = config.Entrypoint[1:]
+	}
+
+	// command
+	if args := append(append([]string{}, entrypointArgs...), config.Cmd...); len(args) > 0 {
+		unit.WriteString(fmt.Sprintf("Exec=%s\n", systemdCommand(args)))
+	}
+
+	// environment variables
+	for _, env := range config.Env {
+		if env == "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin" {
+			continue
+		}
+		unit.WriteString(fmt.Sprintf("Environment=%s\n", systemdQuote(env)))
+	}
+
+	// labels
+	for _, key := range sortedKeys(config.Labels) {
+		unit.WriteString(fmt.Sprintf("Label=%s\n", systemdQuote(key+"="+config.Labels[key])))
+	}
+
+	// host-to-IP mapping
+	for _, host := range hostConfig.ExtraHosts {
+		unit.WriteString(fmt.Sprintf("AddHost=%s\n", systemdQuote(host)))
+	}
+
+	// capabilities
+	for _, cap := range hostConfig.CapAdd {
+		unit.WriteString(fmt.Sprintf("AddCapability=%s\n", cap))
+	}
+	for _, cap := range hostConfig.CapDrop {
+		unit.WriteString(fmt.Sprintf("DropCapability=%s\n", cap))
+	}
+
+	// readonly root fs
+	if hostConfig.ReadonlyRootfs {
+		unit.WriteString("ReadOnly=true\n")
+	}
+
+	// network
+	if ref, ok := graph.ref(cname, refNetwork); ok {
+		unit.WriteString(fmt.Sprintf("Network=container:%s\n", ref.Target))
+	} else if mode := hostConfig.NetworkMode; mode.IsHost() || mode.IsNone() {
+		unit.WriteString(fmt.Sprintf("Network=%s\n", mode))
+	}
+	for _, name := range userNetworks(containerJSON) {
+		network := name
+		if !opts.External {
+			network += ".network"
+		}
+		// podman 在网络名称之后指定别名、固定 IP 和 MAC 地址
+		var options []string
+		if endpoint := networkEndpoint(containerJSON, name); endpoint != nil {
+			for _, alias := range endpointAliases(containerJSON, endpoint) {
+				options = append(options, "alias="+alias)
+			}
+			if ipam := endpoint.IPAMConfig; ipam != nil {
+				if ipam.IPv4Address != "" {
+					options = append(options, "ip="+ipam.IPv4Address)
+				}
+				if ipam.IPv6Address != "" {
+					options = append(options, "ip6="+ipam.IPv6Address)
+				}
+			}
+			if mac := customMacAddress(endpoint); mac != "" {
+				options = append(options, "mac="+mac)
+			}
+		}
+		if len(options) > 0 {
+			network += ":" + strings.Join(options, ",")
+		}
+		unit.WriteString(fmt.Sprintf("Network=%s\n", systemdQuote(network)))
+	}
+
+	// dns
+	for _, dns := range hostConfig.DNS {
+		unit.WriteString(fmt.Sprintf("DNS=%s\n", dns))
+	}
+
+	// port mapping
+	addedPorts := make(map[string]bool)
+	for _, port := range containerPorts(containerJSON) {
+		for _, binding := range containerJSON.NetworkSettings.Ports[port] {
+			portMapping := portSpec(port, binding)
+			if !addedPorts[portMapping] {
+				addedPorts[portMapping] = true
+				unit.WriteString(fmt.Sprintf("PublishPort=%s\n", portMapping))
+			}
+		}
+	}
+	for _, port := range exposedOnlyPorts(containerJSON) {
+		unit.WriteString(fmt.Sprintf("ExposeHostPort=%s\n", port))
+	}
+
+	// mount
+	for _, mount := range containerJSON.Mounts {
+		if m := hostMount(containerJSON, mount.Destination); needsLongSyntax(m) {
+			spec := *m
+			if spec.Type == "volume" && spec.Source != "" && !opts.External {
+				spec.Source += ".volume"
+			}
+			unit.WriteString(fmt.Sprintf("Mount=%s\n", systemdQuote(mountSpec(spec))))
+			continue
+		}
+		var volume string
+		switch {
+		case mount.Type == "bind":
+			volume = mount.Source + ":" + mount.Destination
+		case mount.Type == "volume" && mount.Name != "" && !isAnonymousVolume(mount.Name):
+			if opts.External {
+				volume = mount.Name + ":" + mount.Destination
+			} else {
+				volume = mount.Name + ".volume:" + mount.Destination
+			}
+		case mount.Type == "volume":
+			volume = mount.Destination
+		default:
+			continue
+		}
+		if options := shortMountOptions(mount); len(options) > 0 {
+			volume += ":" + strings.Join(options, ",")
+		}
+		unit.WriteString(fmt.Sprintf("Volume=%s\n", systemdQuote(volume)))
+	}
+
+	// devices
+	for _, device := range hostConfig.Devices {
+		unit.WriteString(fmt.Sprintf("AddDevice=%s\n", systemdQuote(device.PathOnHost+":"+device.PathInContainer)))
+	}
+
+	// ulimits, sysctls and tmpfs
+	for _, ulimit := range hostConfig.Ulimits {
+		unit.WriteString(fmt.Sprintf("Ulimit=%s\n", ulimitSpec(ulimit)))
+	}
+	for _, key := range sortedKeys(hostConfig.Sysctls) {
+		unit.WriteString(fmt.Sprintf("Sysctl=%s\n", systemdQuote(key+"="+hostConfig.Sysctls[key])))
+	}
+	for _, path := range sortedKeys(hostConfig.Tmpfs) {
+		unit.WriteString(fmt.Sprintf("Tmpfs=%s\n", systemdQuote(tmpfsSpec(path, hostConfig.Tmpfs[path]))))
+	}
+
+	// log driver
+	if hostConfig.LogConfig.Type != "" && hostConfig.LogConfig.Type != "json-file" {
+		unit.WriteString(fmt.Sprintf("LogDriver=%s\n", hostConfig.LogConfig.Type))
+	}
+
+	// quadlet 没有对应配置项的参数
+	var podmanArgs []string
+	if hostConfig.Privileged {
+		podmanArgs = append(podmanArgs, "--privileged")
+	}
+	for _, option := range resourceOptions(hostConfig) {
+		podmanArgs = append(podmanArgs, option[0]+"="+option[1])
+	}
+	if hostConfig.OomKillDisable != nil && *hostConfig.OomKillDisable {
+		podmanArgs = append(podmanArgs, "--oom-kill-disable")
+	}
+	if hostConfig.ShmSize > 0 && hostConfig.ShmSize != defaultShmSize {
+		podmanArgs = append(podmanArgs, "--shm-size="+bytesSize(hostConfig.ShmSize))
+	}
+	for _, key := range sortedKeys(hostConfig.StorageOpt) {
+		podmanArgs = append(podmanArgs, "--storage-opt="+key+"="+hostConfig.StorageOpt[key])
+	}
+	if hostConfig.OomScoreAdj != 0 {
+		podmanArgs = append(podmanArgs, fmt.Sprintf("--oom-score-adj=%d", hostConfig.OomScoreAdj))
+	}
+	if ref, ok := graph.ref(cname, refPid); ok {
+		podmanArgs = append(podmanArgs, "--pid=container:"+ref.Target)
+	} else if hostConfig.PidMode != "" {
+		podmanArgs = append(podmanArgs, "--pid="+string(hostConfig.PidMode))
+	}
+	if ref, ok := graph.ref(cname, refIpc); ok {
+		podmanArgs = append(podmanArgs, "--ipc=container:"+ref.Target)
+	} else if ipcMode := hostConfig.IpcMode; ipcMode.IsHost() || ipcMode.IsNone() {
+		podmanArgs = append(podmanArgs, "--ipc="+string(ipcMode))
+	}
+	for _, ref := range graph.refsOf(cname, refVolumesFrom) {
+		from := ref.Target
+		if ref.Option != "" {
+			from += ":" + ref.Option
+		}
+		podmanArgs = append(podmanArgs, "--volumes-from="+from)
+	}
+	for _, key := range sortedKeys(hostConfig.LogConfig.Config) {
+		podmanArgs = append(podmanArgs, "--log-opt="+key+"="+hostConfig.LogConfig.Config[key])
+	}
+	if len(podmanArgs) > 0 {
+		unit.WriteString(fmt.Sprintf("PodmanArgs=%s\n", systemdCommand(podmanArgs)))
+	}
+
+	// [Service]
+	if restart := systemdRestart(hostConfig.RestartPolicy); restart != "" {
+		unit.WriteString("\n[Service]\n")
+		unit.WriteString(fmt.Sprintf("Restart=%s\n", restart))
+	}
+
+	// [Install]
+	unit.WriteString("\n[Install]\n")
+	unit.WriteString("WantedBy=default.target\n")
+
+	return unit.String()
+}
+
+// generateQuadletNetwork 生成自定义网络的 .network 文件，没有网络详细信息时只保留名称
+func generateQuadletNetwork(name string, opts *ExportOptions) string {
+	var unit strings.Builder
+	unit.WriteString("[Network]\n")
+	// 指定 NetworkName 避免 quadlet 为网络加上 systemd- 前缀
+	unit.WriteString(fmt.Sprintf("NetworkName=%s\n", name))
+
+	networkJSON, ok := opts.Networks[name]
+	if !ok {
+		return unit.String()
+	}
+	if networkJSON.Driver != "" {
+		unit.WriteString(fmt.Sprintf("Driver=%s\n", networkJSON.Driver))
+	}
+	if networkJSON.Internal {
+		unit.WriteString("Internal=true\n")
+	}
+	if networkJSON.EnableIPv6 {
+		unit.WriteString("IPv6=true\n")
+	}
+	for _, ipam := range networkJSON.IPAM.Config {
+		if ipam.Subnet != "" {
+			unit.WriteString(fmt.Sprintf("Subnet=%s\n", ipam.Subnet))
+		}
+		if ipam.Gateway != "" {
+			unit.WriteString(fmt.Sprintf("Gateway=%s\n", ipam.Gateway))
+		}
+		if ipam.IPRange != "" {
+			unit.WriteString(fmt.Sprintf("IPRange=%s\n", ipam.IPRange))
+		}
+	}
+	if networkJSON.IPAM.Driver != "" && networkJSON.IPAM.Driver != "default" {
+		unit.WriteString(fmt.Sprintf("IPAMDriver=%s\n", networkJSON.IPAM.Driver))
+	}
+	for _, key := range sortedKeys(networkJSON.Options) {
+		unit.WriteString(fmt.Sprintf("Options=%s\n", systemdQuote(key+"="+networkJSON.Options[key])))
+	}
+	labels := userLabels(networkJSON.Labels)
+	for _, key := range sortedKeys(labels) {
+		unit.WriteString(fmt.Sprintf("Label=%s\n", systemdQuote(key+"="+labels[key])))
+	}
+	return unit.String()
+}
+
+// generateQuadletVolume 生成命名卷的 .volume 文件，local 驱动的 type、device、o 选项使用对应的键
+func generateQuadletVolume(volumeJSON volume.Volume) string {
+	var unit strings.Builder
+	unit.WriteString("[Volume]\n")
+	unit.WriteString(fmt.Sprintf("VolumeName=%s\n", volumeJSON.Name))
+	if volumeJSON.Driver != "" && volumeJSON.Driver != "local" {
+		unit.WriteString(fmt.Sprintf("Driver=%s\n", volumeJSON.Driver))
+	}
+	var podmanArgs []string
+	for _, key := range sortedKeys(volumeJSON.Options) {
+		value := volumeJSON.Options[key]
+		switch {
+		case key == "type" && volumeJSON.Driver == "local":
+			unit.WriteString(fmt.Sprintf("Type=%s\n", systemdQuote(value)))
+		case key == "device" && volumeJSON.Driver == "local":
+			unit.WriteString(fmt.Sprintf("Device=%s\n", systemdQuote(value)))
+		case key == "o" && volumeJSON.Driver == "local":
+			unit.WriteString(fmt.Sprintf("Options=%s\n", systemdQuote(value)))
+		default:
+			podmanArgs = append(podmanArgs, "--opt="+key+"="+value)
+		}
+	}
+	labels := userLabels(volumeJSON.Labels)
+	for _, key := range sortedKeys(labels) {
+		unit.WriteString(fmt.Sprintf("Label=%s\n", systemdQuote(key+"="+labels[key])))
+	}
+	if len(podmanArgs) > 0 {
+		unit.WriteString(fmt.Sprintf("PodmanArgs=%s\n", systemdCommand(podmanArgs)))
+	}
+	return unit.String()
+}
+
+// qualifiedImage 补全镜像的仓库地址，podman 默认不会把短名称解析到 docker.io
+func qualifiedImage(image string) string {
+	// sha256: 开头的镜像 ID 会被当作名称为 sha256 的镜像解析
+	if strings.HasPrefix(image, "sha256:") {
+		return image
+	}
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		// 镜像 ID 等无法解析的引用原样返回
+		return image
+	}
+	return named.String()
+}
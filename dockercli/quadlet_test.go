@@ -0,0 +1,96 @@
+package dockercli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/go-connections/nat"
+)
+
+func TestQualifiedImage(t *testing.T) {
+	tests := []struct {
+		image, want string
+	}{
+		{"nginx", "docker.io/library/nginx"},
+		{"nginx:1.25", "docker.io/library/nginx:1.25"},
+		{"grafana/grafana", "docker.io/grafana/grafana"},
+		{"quay.io/prometheus/prometheus:v2", "quay.io/prometheus/prometheus:v2"},
+		// 镜像 ID 原样返回
+		{"sha256:0123456789ab", "sha256:0123456789ab"},
+	}
+	for _, tt := range tests {
+		if got := qualifiedImage(tt.image); got != tt.want {
+			t.Errorf("qualifiedImage(%q) = %q, want %q", tt.image, got, tt.want)
+		}
+	}
+}
+
+func TestQuadletContainer(t *testing.T) {
+	db := volumeContainer()
+	web := multiNetworkContainer()
+	web.Config.Entrypoint = []string{"/docker-entrypoint.sh", "--verbose"}
+	web.Config.Cmd = []string{"nginx", "-g", "daemon off;"}
+	web.Config.Env = append(web.Config.Env, "GREETING=hello $USER 100%")
+	web.Config.Labels = map[string]string{"description": "Web server"}
+	web.HostConfig.CapAdd = []string{"NET_ADMIN"}
+	web.HostConfig.RestartPolicy = container.RestartPolicy{Name: container.RestartPolicyUnlessStopped}
+	web.HostConfig.VolumesFrom = []string{"db:ro"}
+	web.HostConfig.Memory = 512 << 20
+	web.NetworkSettings.Ports = nat.PortMap{"80/tcp": {{HostPort: "8080"}}}
+
+	opts := &ExportOptions{Format: "quadlet", Volumes: map[string]volume.Volume{"dbdata": dbdataVolume()}}
+	dump, err := ParseContainers([]types.ContainerJSON{web, db}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := dump.(Files)
+	for _, name := range []string{"web.container", "db.container", "app.network", "backend.network", "dbdata.volume"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing %s in %q", name, files.Names())
+		}
+	}
+
+	unit := files["web.container"]
+	for _, want := range []string{
+		"[Unit]\nDescription=Web server\nRequires=db.service\nAfter=db.service\n",
+		"ContainerName=web\nImage=docker.io/library/nginx:latest\n",
+		"Entrypoint=/docker-entrypoint.sh\nExec=--verbose nginx -g \"daemon off;\"\n",
+		// $ 和 % 需要转义
+		"Environment=\"GREETING=hello $$USER 100%%\"\n",
+		"AddCapability=NET_ADMIN\n",
+		"Network=app.network:alias=frontend,ip=172.20.0.10,ip6=fd00::10,mac=92:d0:c6:0a:29:33\n",
+		"Network=backend.network:alias=api,ip=172.21.0.10\n",
+		"PublishPort=8080:80\n",
+		"PodmanArgs=--memory=512m --volumes-from=db:ro\n",
+		"[Service]\nRestart=always\n",
+		"[Install]\nWantedBy=default.target\n",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("missing %q in:\n%s", want, unit)
+		}
+	}
+	if want := "Volume=dbdata.volume:/var/lib/db\nVolume=/cache\n"; !strings.Contains(files["db.container"], want) {
+		t.Errorf("missing %q in:\n%s", want, files["db.container"])
+	}
+}
+
+func TestQuadletExternal(t *testing.T) {
+	opts := &ExportOptions{Format: "quadlet", External: true}
+	dump, err := ParseContainers([]types.ContainerJSON{volumeContainer(), multiNetworkContainer()}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := dump.(Files)
+	// 引用已存在的网络和卷，不生成 .network 和 .volume 文件
+	if names := files.Names(); len(names) != 2 {
+		t.Errorf("files = %q, want only the .container files", names)
+	}
+	for _, want := range []string{"Volume=dbdata:/var/lib/db\n", "Network=app:alias=frontend"} {
+		if !strings.Contains(files["db.container"]+files["web.container"], want) {
+			t.Errorf("missing %q in:\n%s%s", want, files["db.container"], files["web.container"])
+		}
+	}
+}
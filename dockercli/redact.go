@@ -0,0 +1,225 @@
+package dockercli
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/fimreal/goutils/ezap"
+)
+
+// SecretsFile json 格式导出时默认写入输出目录的密钥文件名
+const SecretsFile = "secrets.env"
+
+// defaultSecretRegexp 内置的敏感信息规则，不区分大小写地匹配变量名或标签名中的子串
+var defaultSecretRegexp = regexp.MustCompile(`(?i)PASSWORD|PASSWD|TOKEN|SECRET|API_?KEY|ACCESS_KEY|PRIVATE_KEY|CREDENTIAL`)
+
+// secretMarkerRegexp 脱敏后写入配置的标记，只包含字母、数字和下划线，各种格式加引号时都不会改变，
+// 生成输出之后再替换为对应格式的占位符
+var secretMarkerRegexp = regexp.MustCompile(`__REDACTED_([A-Za-z0-9]+(?:_[A-Za-z0-9]+)*)__`)
+
+// secretPlaceholderRegexp JSON 中脱敏后的值，整个值为 ${VAR}
+var secretPlaceholderRegexp = regexp.MustCompile(`^\$\{([A-Za-z0-9]+(?:_[A-Za-z0-9]+)*)\}$`)
+
+// placeholderInvalidRegexp 占位符变量名中不允许的字符
+var placeholderInvalidRegexp = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// Redactor 将环境变量和标签中的敏感值替换为 ${VAR} 占位符，并记录原始值
+type Redactor struct {
+	patterns []*regexp.Regexp
+	Secrets  map[string]string // 占位符变量名到原始值的映射
+}
+
+// NewRedactor 创建 Redactor，patterns 为配置文件中用户指定的正则表达式，与内置规则一起匹配变量名和标签名
+func NewRedactor(patterns []string) (*Redactor, error) {
+	r := &Redactor{patterns: []*regexp.Regexp{defaultSecretRegexp}, Secrets: make(map[string]string)}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// isSecret 判断变量名或标签名是否匹配敏感信息规则
+func (r *Redactor) isSecret(name string) bool {
+	for _, re := range r.patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactContainers 返回环境变量和标签中的敏感值替换为脱敏标记的容器副本，原始数据不变
+func (r *Redactor) RedactContainers(containersJSON []types.ContainerJSON) []types.ContainerJSON {
+	redacted := make([]types.ContainerJSON, 0, len(containersJSON))
+	for _, containerJSON := range containersJSON {
+		if containerJSON.Config == nil {
+			redacted = append(redacted, containerJSON)
+			continue
+		}
+		cname := strings.TrimPrefix(containerJSON.Name, "/")
+		config := *containerJSON.Config
+
+		config.Env = make([]string, len(containerJSON.Config.Env))
+		for i, env := range containerJSON.Config.Env {
+			name, value, found := strings.Cut(env, "=")
+			if found && value != "" && r.isSecret(name) {
+				env = name + "=" + r.placeholder(cname, name, value)
+			}
+			config.Env[i] = env
+		}
+
+		if containerJSON.Config.Labels != nil {
+			config.Labels = make(map[string]string, len(containerJSON.Config.Labels))
+			for key, value := range containerJSON.Config.Labels {
+				if value != "" && r.isSecret(key) {
+					value = r.placeholder(cname, strings.ToUpper(key), value)
+				}
+				config.Labels[key] = value
+			}
+		}
+
+		containerJSON.Config = &config
+		redacted = append(redacted, containerJSON)
+	}
+	return redacted
+}
+
+// placeholder 记录敏感值并返回对应的脱敏标记，不同容器中同名但值不同的变量加上容器名称前缀
+func (r *Redactor) placeholder(cname, name, value string) string {
+	variable := placeholderName(name)
+	if existing, ok := r.Secrets[variable]; ok && existing != value {
+		variable = placeholderName(strings.ToUpper(cname) + "_" + name)
+		for i := 2; ; i++ {
+			existing, ok := r.Secrets[variable]
+			if !ok || existing == value {
+				break
+			}
+			variable = placeholderName(fmt.Sprintf("%s_%s_%d", strings.ToUpper(cname), name, i))
+		}
+	}
+	r.Secrets[variable] = value
+	return "__REDACTED_" + variable + "__"
+}
+
+// placeholderName 将变量名或标签名转换为只包含字母、数字和单个下划线的占位符变量名
+func placeholderName(name string) string {
+	variable := strings.Trim(placeholderInvalidRegexp.ReplaceAllString(strings.ReplaceAll(name, "_", "."), "_"), "_")
+	if variable == "" {
+		return "SECRET"
+	}
+	if variable[0] >= '0' && variable[0] <= '9' {
+		return "SECRET_" + variable
+	}
+	return variable
+}
+
+// WriteSecretsFile 将脱敏的原始值以 KEY='value' 的形式写入权限为 0600 的文件，
+// 可以被 shell source，也可以作为 docker compose 的 .env 文件
+func (r *Redactor) WriteSecretsFile(filename string) error {
+	var secrets strings.Builder
+	for _, variable := range sortedKeys(r.Secrets) {
+		secrets.WriteString(variable + "=" + shellQuote(r.Secrets[variable]) + "\n")
+	}
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	// 文件已存在时 OpenFile 不会修改权限
+	if err := file.Chmod(0600); err != nil {
+		return err
+	}
+	if _, err := file.WriteString(secrets.String()); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// LoadSecretsFile 读取 WriteSecretsFile 写入的文件，返回占位符变量名到原始值的映射
+func LoadSecretsFile(filename string) (map[string]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	// 值按照 shell 的规则加了引号，可能包含换行
+	commands, err := splitShellCommands(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	secrets := make(map[string]string)
+	for _, command := range commands {
+		name, value, found := strings.Cut(command[0].Value, "=")
+		if len(command) != 1 || !found || !isShellName(name) {
+			return nil, fmt.Errorf("%s:%d: invalid secret, expected KEY='value'", filename, command[0].Line)
+		}
+		secrets[name] = value
+	}
+	return secrets, nil
+}
+
+// RestoreSecrets 将 JSON 导入的容器中整个值为 ${VAR} 占位符的环境变量和标签替换为 secrets 中的原始值，
+// JSON 导入不做变量替换，找不到原始值的占位符会原样保留并给出警告
+func RestoreSecrets(specs []ContainerSpec, secrets map[string]string) {
+	restore := func(cname, name, value string) string {
+		match := secretPlaceholderRegexp.FindStringSubmatch(value)
+		if match == nil {
+			return value
+		}
+		if secret, ok := secrets[match[1]]; ok {
+			return secret
+		}
+		ezap.Warnf("%s: %s is a redacted placeholder without a value, set --secrets-file to the file written by export", cname, name)
+		return value
+	}
+	for _, spec := range specs {
+		for i, env := range spec.Config.Env {
+			if name, value, found := strings.Cut(env, "="); found {
+				spec.Config.Env[i] = name + "=" + restore(spec.Name, name, value)
+			}
+		}
+		for key, value := range spec.Config.Labels {
+			spec.Config.Labels[key] = restore(spec.Name, key, value)
+		}
+	}
+}
+
+// secretPlaceholderFormat 返回输出格式中占位符的写法，HCL 和 systemd 中 $ 需要转义才能原样保留
+func secretPlaceholderFormat(format string) string {
+	switch format {
+	case "nomad", "terraform", "tf", "systemd", "quadlet":
+		return "$${%s}"
+	}
+	return "${%s}"
+}
+
+// replaceSecretMarkers 将 s 中的脱敏标记替换为占位符
+func replaceSecretMarkers(s, placeholder string) string {
+	return secretMarkerRegexp.ReplaceAllStringFunc(s, func(marker string) string {
+		return fmt.Sprintf(placeholder, secretMarkerRegexp.FindStringSubmatch(marker)[1])
+	})
+}
+
+// expandSecretMarkers 将格式化后的输出中的脱敏标记替换为对应格式的占位符
+func expandSecretMarkers(dump interface{}, format string) interface{} {
+	placeholder := secretPlaceholderFormat(format)
+	switch t := dump.(type) {
+	case string:
+		return replaceSecretMarkers(t, placeholder)
+	case map[string]string:
+		for name, content := range t {
+			t[name] = replaceSecretMarkers(content, placeholder)
+		}
+	case Files:
+		for name, content := range t {
+			t[name] = replaceSecretMarkers(content, placeholder)
+		}
+	}
+	return dump
+}
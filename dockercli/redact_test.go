@@ -0,0 +1,169 @@
+package dockercli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestRedactContainers(t *testing.T) {
+	web := testContainer("aaaaaaaaaaaa1111", "web", "DB_PASSWORD=s3cret", "API_KEY=k1", "MODE=prod", "EMPTY_TOKEN=")
+	web.Config.Labels = map[string]string{"app.secret": "l4bel", "app.name": "web"}
+	db := testContainer("bbbbbbbbbbbb2222", "db", "DB_PASSWORD=other")
+
+	redactor, err := NewRedactor([]string{"^MODE$"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	redacted := redactor.RedactContainers([]types.ContainerJSON{web, db})
+
+	wantWeb := []string{
+		"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+		"DB_PASSWORD=__REDACTED_DB_PASSWORD__",
+		"API_KEY=__REDACTED_API_KEY__",
+		"MODE=__REDACTED_MODE__",
+		"EMPTY_TOKEN=",
+	}
+	if !reflect.DeepEqual(redacted[0].Config.Env, wantWeb) {
+		t.Errorf("web env = %q, want %q", redacted[0].Config.Env, wantWeb)
+	}
+	if got := redacted[0].Config.Labels; got["app.secret"] != "__REDACTED_APP_SECRET__" || got["app.name"] != "web" {
+		t.Errorf("web labels = %q", got)
+	}
+	// 不同容器中同名但值不同的变量加上容器名称前缀
+	if got := redacted[1].Config.Env[1]; got != "DB_PASSWORD=__REDACTED_DB_DB_PASSWORD__" {
+		t.Errorf("db env = %q", got)
+	}
+	// 原始数据不变
+	if web.Config.Env[1] != "DB_PASSWORD=s3cret" || web.Config.Labels["app.secret"] != "l4bel" {
+		t.Errorf("original container was modified: %q %q", web.Config.Env, web.Config.Labels)
+	}
+
+	wantSecrets := map[string]string{"DB_PASSWORD": "s3cret", "DB_DB_PASSWORD": "other", "API_KEY": "k1", "MODE": "prod", "APP_SECRET": "l4bel"}
+	if !reflect.DeepEqual(redactor.Secrets, wantSecrets) {
+		t.Errorf("secrets = %q, want %q", redactor.Secrets, wantSecrets)
+	}
+}
+
+func TestNewRedactorInvalidPattern(t *testing.T) {
+	if _, err := NewRedactor([]string{"("}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestRedactFormats(t *testing.T) {
+	tests := []struct {
+		format      string
+		placeholder string
+	}{
+		{"command", `"DB_PASSWORD=${DB_PASSWORD}"`},
+		{"compose", "DB_PASSWORD=${DB_PASSWORD}"},
+		{"json", `"DB_PASSWORD=${DB_PASSWORD}"`},
+		{"kubernetes", "${DB_PASSWORD}"},
+		{"quadlet", "DB_PASSWORD=$${DB_PASSWORD}"},
+		{"systemd", "$${DB_PASSWORD}"},
+		{"nomad", "$${DB_PASSWORD}"},
+		{"ansible", "${DB_PASSWORD}"},
+		{"terraform", "DB_PASSWORD=$${DB_PASSWORD}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			web := testContainer("aaaaaaaaaaaa1111", "web", "DB_PASSWORD=tok3n", "MODE=prod")
+			redactor, _ := NewRedactor(nil)
+			out := exportString(t, []types.ContainerJSON{web}, &ExportOptions{Format: tt.format, Redactor: redactor})
+			if strings.Contains(out, "tok3n") {
+				t.Errorf("secret leaked into %s output:\n%s", tt.format, out)
+			}
+			if strings.Contains(out, "__REDACTED_") {
+				t.Errorf("marker left in %s output:\n%s", tt.format, out)
+			}
+			if !strings.Contains(out, tt.placeholder) {
+				t.Errorf("%s output does not contain %s:\n%s", tt.format, tt.placeholder, out)
+			}
+		})
+	}
+}
+
+func TestSecretsFileRoundTrip(t *testing.T) {
+	redactor, _ := NewRedactor(nil)
+	redactor.Secrets["DB_PASSWORD"] = "it's $HOME \"q\"\nline2"
+	redactor.Secrets["API_KEY"] = "plain"
+
+	filename := filepath.Join(t.TempDir(), "secrets.env")
+	// 已存在的文件也会被改为 0600
+	writeTestFile(t, filepath.Dir(filename), filepath.Base(filename), "")
+	if err := redactor.WriteSecretsFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("secrets file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	secrets, err := LoadSecretsFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(secrets, redactor.Secrets) {
+		t.Errorf("LoadSecretsFile = %q, want %q", secrets, redactor.Secrets)
+	}
+}
+
+func TestRedactRoundTrip(t *testing.T) {
+	secret := "it's $HOME \"q\""
+	web := testContainer("aaaaaaaaaaaa1111", "web", "DB_PASSWORD="+secret, "MODE=prod")
+	web.Config.Labels = map[string]string{"app.token": secret}
+	want := []string{"DB_PASSWORD=" + secret, "MODE=prod"}
+
+	for _, format := range []string{"command", "compose", "json"} {
+		t.Run(format, func(t *testing.T) {
+			redactor, _ := NewRedactor(nil)
+			opts := &ExportOptions{Format: format, SingleFile: true, Redactor: redactor}
+			dir := t.TempDir()
+			filename := writeTestFile(t, dir, "export."+format, exportString(t, []types.ContainerJSON{web}, opts))
+
+			var specs []ContainerSpec
+			var err error
+			switch format {
+			case "command":
+				// 执行命令前 source 密钥文件
+				for name, value := range redactor.Secrets {
+					t.Setenv(name, value)
+				}
+				specs, err = LoadCommands(filename)
+			case "compose":
+				for name, value := range redactor.Secrets {
+					t.Setenv(name, value)
+				}
+				specs, err = LoadCompose(filename)
+			case "json":
+				data, readErr := os.ReadFile(filename)
+				if readErr != nil {
+					t.Fatal(readErr)
+				}
+				specs, err = LoadContainersJSON(data)
+				RestoreSecrets(specs, redactor.Secrets)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(specs) != 1 {
+				t.Fatalf("got %d containers, want 1", len(specs))
+			}
+			if got := envOf(specs[0]); !reflect.DeepEqual(got, want) {
+				t.Errorf("env = %q, want %q", got, want)
+			}
+			// compose 只在 swarm 的 deploy 中导出标签
+			if got := specs[0].Config.Labels["app.token"]; format != "compose" && got != secret {
+				t.Errorf("label = %q, want %q", got, secret)
+			}
+		})
+	}
+}
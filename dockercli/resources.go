@@ -0,0 +1,230 @@
+package dockercli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/blkiodev"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+	"github.com/fimreal/goutils/ezap"
+)
+
+// resourceOptions 返回资源限制对应的 docker run 参数，容量使用 512m 这样的单位
+func resourceOptions(hostConfig *container.HostConfig) [][]string {
+	var options [][]string
+	add := func(words ...string) {
+		options = append(options, words)
+	}
+
+	// cpu
+	if hostConfig.NanoCPUs > 0 {
+		add("--cpus", strconv.FormatFloat(float64(hostConfig.NanoCPUs)/1e9, 'f', -1, 64))
+	}
+	if hostConfig.CPUShares > 0 {
+		add("--cpu-shares", strconv.FormatInt(hostConfig.CPUShares, 10))
+	}
+	if hostConfig.CPUPeriod > 0 {
+		add("--cpu-period", strconv.FormatInt(hostConfig.CPUPeriod, 10))
+	}
+	if hostConfig.CPUQuota != 0 {
+		add("--cpu-quota", strconv.FormatInt(hostConfig.CPUQuota, 10))
+	}
+	if hostConfig.CPURealtimePeriod > 0 {
+		add("--cpu-rt-period", strconv.FormatInt(hostConfig.CPURealtimePeriod, 10))
+	}
+	if hostConfig.CPURealtimeRuntime > 0 {
+		add("--cpu-rt-runtime", strconv.FormatInt(hostConfig.CPURealtimeRuntime, 10))
+	}
+	if hostConfig.CpusetCpus != "" {
+		add("--cpuset-cpus", hostConfig.CpusetCpus)
+	}
+	if hostConfig.CpusetMems != "" {
+		add("--cpuset-mems", hostConfig.CpusetMems)
+	}
+
+	// memory
+	if hostConfig.Memory > 0 {
+		add("--memory", bytesSize(hostConfig.Memory))
+	}
+	if hostConfig.MemoryReservation > 0 {
+		add("--memory-reservation", bytesSize(hostConfig.MemoryReservation))
+	}
+	if swap := memorySwap(hostConfig.MemorySwap); swap != "" {
+		add("--memory-swap", swap)
+	}
+	if hostConfig.MemorySwappiness != nil && *hostConfig.MemorySwappiness >= 0 {
+		add("--memory-swappiness", strconv.FormatInt(*hostConfig.MemorySwappiness, 10))
+	}
+	if hostConfig.KernelMemory > 0 {
+		add("--kernel-memory", bytesSize(hostConfig.KernelMemory))
+	}
+	if hostConfig.PidsLimit != nil && *hostConfig.PidsLimit > 0 {
+		add("--pids-limit", strconv.FormatInt(*hostConfig.PidsLimit, 10))
+	}
+
+	// block io
+	if hostConfig.BlkioWeight > 0 {
+		add("--blkio-weight", strconv.Itoa(int(hostConfig.BlkioWeight)))
+	}
+	for _, device := range hostConfig.BlkioWeightDevice {
+		add("--blkio-weight-device", fmt.Sprintf("%s:%d", device.Path, device.Weight))
+	}
+	for _, device := range hostConfig.BlkioDeviceReadBps {
+		add("--device-read-bps", device.Path+":"+bytesSize(int64(device.Rate)))
+	}
+	for _, device := range hostConfig.BlkioDeviceWriteBps {
+		add("--device-write-bps", device.Path+":"+bytesSize(int64(device.Rate)))
+	}
+	for _, device := range hostConfig.BlkioDeviceReadIOps {
+		add("--device-read-iops", fmt.Sprintf("%s:%d", device.Path, device.Rate))
+	}
+	for _, device := range hostConfig.BlkioDeviceWriteIOps {
+		add("--device-write-iops", fmt.Sprintf("%s:%d", device.Path, device.Rate))
+	}
+	return options
+}
+
+// memorySwap 返回 --memory-swap 的值，-1 表示不限制 swap，未设置时返回空字符串
+func memorySwap(swap int64) string {
+	switch {
+	case swap == -1:
+		return "-1"
+	case swap > 0:
+		return bytesSize(swap)
+	}
+	return ""
+}
+
+// generateComposeResources 生成 compose 服务的资源限制配置
+func generateComposeResources(cname string, hostConfig *container.HostConfig) string {
+	var resources strings.Builder
+
+	// cpu
+	if hostConfig.NanoCPUs > 0 {
+		resources.WriteString(fmt.Sprintf("    cpus: '%s'\n", strconv.FormatFloat(float64(hostConfig.NanoCPUs)/1e9, 'f', -1, 64)))
+	}
+	if hostConfig.CPUShares > 0 {
+		resources.WriteString(fmt.Sprintf("    cpu_shares: %d\n", hostConfig.CPUShares))
+	}
+	if hostConfig.CPUPeriod > 0 {
+		resources.WriteString(fmt.Sprintf("    cpu_period: %d\n", hostConfig.CPUPeriod))
+	}
+	if hostConfig.CPUQuota != 0 {
+		resources.WriteString(fmt.Sprintf("    cpu_quota: %d\n", hostConfig.CPUQuota))
+	}
+	if hostConfig.CPURealtimePeriod > 0 {
+		resources.WriteString(fmt.Sprintf("    cpu_rt_period: %d\n", hostConfig.CPURealtimePeriod))
+	}
+	if hostConfig.CPURealtimeRuntime > 0 {
+		resources.WriteString(fmt.Sprintf("    cpu_rt_runtime: %d\n", hostConfig.CPURealtimeRuntime))
+	}
+	if hostConfig.CpusetCpus != "" {
+		resources.WriteString(fmt.Sprintf("    cpuset: %s\n", yamlQuote(hostConfig.CpusetCpus)))
+	}
+	if hostConfig.CpusetMems != "" {
+		ezap.Warnf("%s: cpuset_mems is not supported by compose, dropped", cname)
+	}
+
+	// memory
+	if hostConfig.Memory > 0 {
+		resources.WriteString(fmt.Sprintf("    mem_limit: %s\n", bytesSize(hostConfig.Memory)))
+	}
+	if hostConfig.MemoryReservation > 0 {
+		resources.WriteString(fmt.Sprintf("    mem_reservation: %s\n", bytesSize(hostConfig.MemoryReservation)))
+	}
+	if swap := memorySwap(hostConfig.MemorySwap); swap != "" {
+		resources.WriteString(fmt.Sprintf("    memswap_limit: %s\n", swap))
+	}
+	if hostConfig.MemorySwappiness != nil && *hostConfig.MemorySwappiness >= 0 {
+		resources.WriteString(fmt.Sprintf("    mem_swappiness: %d\n", *hostConfig.MemorySwappiness))
+	}
+	if hostConfig.KernelMemory > 0 {
+		ezap.Warnf("%s: kernel memory limit is not supported by compose, dropped", cname)
+	}
+	if hostConfig.PidsLimit != nil && *hostConfig.PidsLimit > 0 {
+		resources.WriteString(fmt.Sprintf("    pids_limit: %d\n", *hostConfig.PidsLimit))
+	}
+
+	// block io
+	var blkio strings.Builder
+	if hostConfig.BlkioWeight > 0 {
+		blkio.WriteString(fmt.Sprintf("      weight: %d\n", hostConfig.BlkioWeight))
+	}
+	if len(hostConfig.BlkioWeightDevice) > 0 {
+		blkio.WriteString("      weight_device:\n")
+		for _, device := range hostConfig.BlkioWeightDevice {
+			blkio.WriteString(fmt.Sprintf("        - path: %s\n", yamlScalar(device.Path)))
+			blkio.WriteString(fmt.Sprintf("          weight: %d\n", device.Weight))
+		}
+	}
+	for _, throttle := range []struct {
+		key     string
+		devices []*blkiodev.ThrottleDevice
+		bytes   bool
+	}{
+		{"device_read_bps", hostConfig.BlkioDeviceReadBps, true},
+		{"device_write_bps", hostConfig.BlkioDeviceWriteBps, true},
+		{"device_read_iops", hostConfig.BlkioDeviceReadIOps, false},
+		{"device_write_iops", hostConfig.BlkioDeviceWriteIOps, false},
+	} {
+		if len(throttle.devices) == 0 {
+			continue
+		}
+		blkio.WriteString(fmt.Sprintf("      %s:\n", throttle.key))
+		for _, device := range throttle.devices {
+			rate := strconv.FormatUint(device.Rate, 10)
+			if throttle.bytes {
+				rate = bytesSize(int64(device.Rate))
+			}
+			blkio.WriteString(fmt.Sprintf("        - path: %s\n", yamlScalar(device.Path)))
+			blkio.WriteString(fmt.Sprintf("          rate: %s\n", rate))
+		}
+	}
+	if blkio.Len() > 0 {
+		resources.WriteString("    blkio_config:\n")
+		resources.WriteString(blkio.String())
+	}
+	return resources.String()
+}
+
+// parseMemorySwap 解析 --memory-swap 的值，-1 表示不限制 swap
+func parseMemorySwap(value string) (int64, error) {
+	if value == "-1" {
+		return -1, nil
+	}
+	return units.RAMInBytes(value)
+}
+
+// parseWeightDevice 解析 path:weight 形式的 blkio 权重
+func parseWeightDevice(value string) (*blkiodev.WeightDevice, error) {
+	path, weight, found := strings.Cut(value, ":")
+	if !found || !strings.HasPrefix(path, "/dev/") {
+		return nil, fmt.Errorf("invalid weight device %q", value)
+	}
+	w, err := strconv.ParseUint(weight, 10, 16)
+	if err != nil || w < 10 || w > 1000 {
+		return nil, fmt.Errorf("invalid weight %q for device %s, the range is from 10 to 1000", weight, path)
+	}
+	return &blkiodev.WeightDevice{Path: path, Weight: uint16(w)}, nil
+}
+
+// parseThrottleDevice 解析 path:rate 形式的 blkio 限速，bytes 为 true 时 rate 可以带容量单位
+func parseThrottleDevice(value string, bytes bool) (*blkiodev.ThrottleDevice, error) {
+	path, rate, found := strings.Cut(value, ":")
+	if !found || !strings.HasPrefix(path, "/dev/") {
+		return nil, fmt.Errorf("invalid throttle device %q", value)
+	}
+	var r int64
+	var err error
+	if bytes {
+		r, err = units.RAMInBytes(rate)
+	} else {
+		r, err = strconv.ParseInt(rate, 10, 64)
+	}
+	if err != nil || r < 0 {
+		return nil, fmt.Errorf("invalid rate %q for device %s", rate, path)
+	}
+	return &blkiodev.ThrottleDevice{Path: path, Rate: uint64(r)}, nil
+}
@@ -0,0 +1,101 @@
+package dockercli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/blkiodev"
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestResourceOptions(t *testing.T) {
+	pids, swappiness, unset := int64(100), int64(10), int64(-1)
+	tests := []struct {
+		name      string
+		resources container.Resources
+		want      [][]string
+	}{
+		{"none", container.Resources{MemorySwappiness: &unset}, nil},
+		{"cpu", container.Resources{NanoCPUs: 1500000000, CPUShares: 512, CPUQuota: -1, CpusetCpus: "0-1"}, [][]string{
+			{"--cpus", "1.5"}, {"--cpu-shares", "512"}, {"--cpu-quota", "-1"}, {"--cpuset-cpus", "0-1"},
+		}},
+		{"memory", container.Resources{Memory: 512 << 20, MemoryReservation: 1 << 30, MemorySwap: 1000, MemorySwappiness: &swappiness, PidsLimit: &pids}, [][]string{
+			{"--memory", "512m"}, {"--memory-reservation", "1g"}, {"--memory-swap", "1000"}, {"--memory-swappiness", "10"}, {"--pids-limit", "100"},
+		}},
+		{"unlimited swap", container.Resources{MemorySwap: -1}, [][]string{{"--memory-swap", "-1"}}},
+		{"block io", container.Resources{
+			BlkioWeight:          300,
+			BlkioWeightDevice:    []*blkiodev.WeightDevice{{Path: "/dev/sda", Weight: 200}},
+			BlkioDeviceReadBps:   []*blkiodev.ThrottleDevice{{Path: "/dev/sda", Rate: 1 << 20}},
+			BlkioDeviceWriteIOps: []*blkiodev.ThrottleDevice{{Path: "/dev/sda", Rate: 100}},
+		}, [][]string{
+			{"--blkio-weight", "300"}, {"--blkio-weight-device", "/dev/sda:200"}, {"--device-read-bps", "/dev/sda:1m"}, {"--device-write-iops", "/dev/sda:100"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resourceOptions(&container.HostConfig{Resources: tt.resources})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// resourceContainer 返回设置了资源限制的容器
+func resourceContainer(resources container.Resources) []types.ContainerJSON {
+	web := testContainer("aaaaaaaaaaaa1111", "web")
+	web.HostConfig.Resources = resources
+	return []types.ContainerJSON{web}
+}
+
+func TestResourcesExport(t *testing.T) {
+	pids := int64(100)
+	tests := []struct {
+		name      string
+		format    string
+		resources container.Resources
+		want      []string
+	}{
+		{"compose sizes", "compose", container.Resources{Memory: 512 << 20, MemoryReservation: 1 << 30, MemorySwap: -1}, []string{
+			"mem_limit: 512m", "mem_reservation: 1g", "memswap_limit: -1",
+		}},
+		{"compose blkio", "compose", container.Resources{
+			BlkioWeight:         300,
+			BlkioWeightDevice:   []*blkiodev.WeightDevice{{Path: "/dev/sda", Weight: 200}},
+			BlkioDeviceReadBps:  []*blkiodev.ThrottleDevice{{Path: "/dev/sda", Rate: 1 << 20}},
+			BlkioDeviceReadIOps: []*blkiodev.ThrottleDevice{{Path: "/dev/sdb", Rate: 100}},
+		}, []string{
+			"    blkio_config:\n      weight: 300\n      weight_device:\n        - path: /dev/sda\n          weight: 200\n",
+			"      device_read_bps:\n        - path: /dev/sda\n          rate: 1m\n",
+			"      device_read_iops:\n        - path: /dev/sdb\n          rate: 100\n",
+		}},
+		{"nomad limit", "nomad", container.Resources{NanoCPUs: 1500000000, Memory: 512 << 20, PidsLimit: &pids}, []string{
+			"cpu        = 1500\n", "memory     = 512\n", "pids_limit = 100\n", "cpu_hard_limit = true\n",
+		}},
+		{"nomad reservation", "nomad", container.Resources{Memory: 1 << 30, MemoryReservation: 256 << 20}, []string{
+			"memory     = 256\n", "memory_max = 1024\n",
+		}},
+		{"nomad small memory", "nomad", container.Resources{Memory: 1000}, []string{"memory     = 1\n"}},
+		{"kubernetes requests", "kubernetes", container.Resources{NanoCPUs: 500000000, CPUShares: 512, Memory: 1 << 30, MemoryReservation: 256 << 20}, []string{
+			"            limits:\n              cpu: 500m\n              memory: 1Gi\n",
+			"            requests:\n              cpu: 500m\n              memory: 256Mi\n",
+		}},
+		{"terraform", "terraform", container.Resources{CPUShares: 512, CpusetCpus: "0-1", Memory: 512 << 20, MemorySwap: 1 << 30}, []string{
+			"  cpu_shares = 512\n", "  cpu_set = \"0-1\"\n", "  memory = 512\n", "  memory_swap = 1024\n",
+		}},
+		{"terraform unlimited swap", "terraform", container.Resources{Memory: 512 << 20, MemorySwap: -1}, []string{"  memory_swap = -1\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := exportString(t, resourceContainer(tt.resources), &ExportOptions{Format: tt.format})
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("missing %q in:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package dockercli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/fimreal/goutils/ezap"
+)
+
+// securityOptions 返回容器的 --security-opt 参数。
+// docker 会把 systempaths=unconfined 转换为空的 MaskedPaths 和 ReadonlyPaths，这里还原为参数
+func securityOptions(hostConfig *container.HostConfig) []string {
+	options := hostConfig.SecurityOpt
+	if !hostConfig.Privileged && hostConfig.MaskedPaths != nil && len(hostConfig.MaskedPaths) == 0 &&
+		hostConfig.ReadonlyPaths != nil && len(hostConfig.ReadonlyPaths) == 0 {
+		options = append(options[:len(options):len(options)], "systempaths=unconfined")
+	}
+	return options
+}
+
+// clearDefaultCgroupns 返回 cgroup 命名空间模式与守护进程默认值相同时清空该配置的容器副本。
+// inspect 结果中总是包含 private 或 host，只有与默认值不同时才需要导出
+func clearDefaultCgroupns(containersJSON []types.ContainerJSON, defaultMode container.CgroupnsMode) []types.ContainerJSON {
+	if defaultMode == "" {
+		defaultMode = container.CgroupnsModePrivate
+	}
+	cleared := make([]types.ContainerJSON, 0, len(containersJSON))
+	for _, containerJSON := range containersJSON {
+		if containerJSON.HostConfig != nil && containerJSON.HostConfig.CgroupnsMode == defaultMode {
+			base := *containerJSON.ContainerJSONBase
+			hostConfig := *containerJSON.HostConfig
+			hostConfig.CgroupnsMode = ""
+			base.HostConfig = &hostConfig
+			containerJSON.ContainerJSONBase = &base
+		}
+		cleared = append(cleared, containerJSON)
+	}
+	return cleared
+}
+
+// isInlineSeccomp 判断 seccomp 参数是否为内联的配置内容，docker 创建容器时会把配置文件的内容写入 SecurityOpt
+func isInlineSeccomp(option string) (string, bool) {
+	key, value, found := strings.Cut(option, "=")
+	if !found {
+		// 旧版本使用 seccomp:value 的形式
+		key, value, found = strings.Cut(option, ":")
+	}
+	if !found || key != "seccomp" || !strings.HasPrefix(strings.TrimSpace(value), "{") {
+		return "", false
+	}
+	return value, true
+}
+
+// extractSeccompProfiles 将容器中内联的 seccomp 配置提取为单独的文件，
+// SecurityOpt 中改为引用相对路径，返回修改后的容器副本和需要写入输出目录的文件
+func extractSeccompProfiles(containersJSON []types.ContainerJSON) ([]types.ContainerJSON, Files) {
+	var files Files
+	extracted := make([]types.ContainerJSON, 0, len(containersJSON))
+	for _, containerJSON := range containersJSON {
+		var options []string
+		changed := false
+		for _, option := range containerJSON.HostConfig.SecurityOpt {
+			profile, ok := isInlineSeccomp(option)
+			if !ok {
+				options = append(options, option)
+				continue
+			}
+
+			cname := strings.TrimPrefix(containerJSON.Name, "/")
+			filename := "seccomp-" + cname + ".json"
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, []byte(profile), "", "  "); err != nil {
+				ezap.Warnf("%s: invalid seccomp profile, keeping it inline: %v", cname, err)
+				options = append(options, option)
+				continue
+			}
+			indented.WriteString("\n")
+			if files == nil {
+				files = make(Files)
+			}
+			files[filename] = indented.String()
+			options = append(options, "seccomp=./"+filename)
+			changed = true
+		}
+
+		if changed {
+			// HostConfig 在 ContainerJSONBase 中，需要复制后再修改，避免影响原始数据
+			base := *containerJSON.ContainerJSONBase
+			hostConfig := *base.HostConfig
+			hostConfig.SecurityOpt = options
+			base.HostConfig = &hostConfig
+			containerJSON.ContainerJSONBase = &base
+		}
+		extracted = append(extracted, containerJSON)
+	}
+	return extracted, files
+}
+
+// loadSecurityOpt 读取 seccomp 参数引用的配置文件，转换为创建容器时使用的内联配置，
+// 相对路径以 baseDir 为基准
+func loadSecurityOpt(option, baseDir string) (string, error) {
+	value, found := strings.CutPrefix(option, "seccomp=")
+	if !found || value == "unconfined" || value == "builtin" || strings.HasPrefix(strings.TrimSpace(value), "{") {
+		return option, nil
+	}
+	path := value
+	if !filepath.IsAbs(path) {
+		path = expandPath(path, baseDir)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("seccomp profile: %w", err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return "", fmt.Errorf("seccomp profile %s: %w", value, err)
+	}
+	return "seccomp=" + compact.String(), nil
+}
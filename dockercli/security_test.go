@@ -0,0 +1,38 @@
+package dockercli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestCgroupnsDefault(t *testing.T) {
+	tests := []struct {
+		mode        container.CgroupnsMode
+		defaultMode container.CgroupnsMode
+		wantCommand string
+		wantCompose string
+	}{
+		{container.CgroupnsModePrivate, "", "", ""},
+		{container.CgroupnsModePrivate, container.CgroupnsModePrivate, "", ""},
+		{container.CgroupnsModeHost, container.CgroupnsModePrivate, "--cgroupns host", "cgroup: host"},
+		{container.CgroupnsModeHost, container.CgroupnsModeHost, "", ""},
+		{container.CgroupnsModePrivate, container.CgroupnsModeHost, "--cgroupns private", "cgroup: private"},
+	}
+	for _, tt := range tests {
+		web := testContainer("aaaaaaaaaaaa1111", "web")
+		web.HostConfig.CgroupnsMode = tt.mode
+		for format, want := range map[string]string{"command": tt.wantCommand, "compose": tt.wantCompose} {
+			out := exportString(t, []types.ContainerJSON{web}, &ExportOptions{Format: format, CgroupnsDefault: tt.defaultMode})
+			if want == "" && strings.Contains(out, "cgroup") || want != "" && !strings.Contains(out, want) {
+				t.Errorf("%s with mode %s and default %q: want %q in\n%s", format, tt.mode, tt.defaultMode, want, out)
+			}
+		}
+		// 原始数据不变
+		if web.HostConfig.CgroupnsMode != tt.mode {
+			t.Errorf("original container was modified")
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package dockercli
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// shellWord shell 分词后的单词，Line 为单词起始位置所在的行号
+type shellWord struct {
+	Value string
+	Line  int
+}
+
+// splitShellCommands 按照 POSIX shell 的规则分词，支持单双引号、反斜杠转义、
+// 反斜杠换行续行和注释，返回以换行、;、&&、||、|、& 分隔的命令列表。
+// 与 shell 一致，未加单引号的 $VAR 和 ${VAR} 会被替换为当前环境中的值，不支持命令替换。
+func splitShellCommands(script string) ([][]shellWord, error) {
+	return tokenizeShell(script, true)
+}
+
+// splitShellWords 与 python shlex.split 一致只处理引号和转义，
+// 不识别注释、命令分隔符和变量，用于 compose 中字符串形式的 command
+func splitShellWords(s string) ([]string, error) {
+	commands, err := tokenizeShell(s, false)
+	if err != nil {
+		return nil, err
+	}
+	var words []string
+	for _, command := range commands {
+		for _, word := range command {
+			words = append(words, word.Value)
+		}
+	}
+	return words, nil
+}
+
+// tokenizeShell 分词实现，script 为 false 时注释、分隔符和 $ 均按普通字符处理
+func tokenizeShell(script string, isScript bool) ([][]shellWord, error) {
+	var (
+		commands [][]shellWord
+		words    []shellWord
+		word     strings.Builder
+		inWord   bool
+		wordLine int
+	)
+	line := 1
+	runes := []rune(script)
+
+	startWord := func() {
+		if !inWord {
+			inWord = true
+			wordLine = line
+		}
+	}
+	endWord := func() {
+		if inWord {
+			words = append(words, shellWord{Value: word.String(), Line: wordLine})
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, words)
+			words = nil
+		}
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\n' && isScript:
+			endCommand()
+			line++
+		case r == '\n':
+			endWord()
+			line++
+		case r == ' ' || r == '\t' || r == '\r':
+			endWord()
+		case r == '#' && !inWord && isScript:
+			// 注释，跳过到行尾
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+		case (r == ';' || r == '&' || r == '|') && isScript:
+			endCommand()
+			if i+1 < len(runes) && (runes[i+1] == r) && r != ';' {
+				i++
+			}
+		case r == '\\':
+			if i+1 >= len(runes) {
+				startWord()
+				word.WriteRune(r)
+				break
+			}
+			i++
+			if runes[i] == '\n' {
+				// 续行
+				line++
+				break
+			}
+			startWord()
+			word.WriteRune(runes[i])
+		case r == '\'':
+			startWord()
+			start := line
+			i++
+			for ; i < len(runes) && runes[i] != '\''; i++ {
+				if runes[i] == '\n' {
+					line++
+				}
+				word.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("line %d: unterminated single quote", start)
+			}
+		case r == '"':
+			startWord()
+			start := line
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				switch runes[i] {
+				case '\n':
+					line++
+					word.WriteRune('\n')
+				case '\\':
+					// 双引号中反斜杠只转义 $ ` " \ 和换行
+					if i+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[i+1]) {
+						i++
+						if runes[i] == '\n' {
+							line++
+							continue
+						}
+					}
+					word.WriteRune(runes[i])
+				case '$', '`':
+					if !isScript {
+						word.WriteRune(runes[i])
+						break
+					}
+					n, err := expandShellVar(runes, i, &word)
+					if err != nil {
+						return nil, fmt.Errorf("line %d: %w", line, err)
+					}
+					i = n
+				default:
+					word.WriteRune(runes[i])
+				}
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("line %d: unterminated double quote", start)
+			}
+		case (r == '$' || r == '`') && isScript:
+			startWord()
+			n, err := expandShellVar(runes, i, &word)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			i = n
+		default:
+			startWord()
+			word.WriteRune(r)
+		}
+	}
+	endCommand()
+	return commands, nil
+}
+
+// scriptDirMarker 表示脚本所在目录的标记，shellQuote 将其转换为 "$(dirname "$0")"，
+// 分词时 "$(dirname "$0")" 转换回该标记，由 LoadCommands 替换为脚本所在的目录
+const scriptDirMarker = "__SCRIPT_DIR__"
+
+// scriptDirSubstitution 唯一支持的命令替换，返回脚本所在的目录
+const scriptDirSubstitution = `$(dirname "$0")`
+
+// expandShellVar 展开 runes[i] 处开始的 $VAR 或 ${VAR}，返回最后一个被处理字符的下标
+func expandShellVar(runes []rune, i int, word *strings.Builder) (int, error) {
+	if strings.HasPrefix(string(runes[i:min(i+len(scriptDirSubstitution), len(runes))]), scriptDirSubstitution) {
+		word.WriteString(scriptDirMarker)
+		return i + len(scriptDirSubstitution) - 1, nil
+	}
+	if runes[i] == '`' || (i+1 < len(runes) && runes[i+1] == '(') {
+		return i, fmt.Errorf("command substitution is not supported")
+	}
+	if i+1 < len(runes) && runes[i+1] == '{' {
+		end := i + 2
+		for end < len(runes) && runes[end] != '}' {
+			end++
+		}
+		if end >= len(runes) {
+			return i, fmt.Errorf("unterminated ${")
+		}
+		name := string(runes[i+2 : end])
+		if !isShellName(name) {
+			return i, fmt.Errorf("unsupported parameter expansion ${%s}", name)
+		}
+		word.WriteString(os.Getenv(name))
+		return end, nil
+	}
+	end := i + 1
+	for end < len(runes) && (runes[end] == '_' || isAlnum(runes[end]) && (end > i+1 || !isDigit(runes[end]))) {
+		end++
+	}
+	if end == i+1 {
+		// 单独的 $ 按字面处理
+		word.WriteRune('$')
+		return i, nil
+	}
+	word.WriteString(os.Getenv(string(runes[i+1 : end])))
+	return end - 1, nil
+}
+
+func isShellName(name string) bool {
+	if name == "" || isDigit(rune(name[0])) {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && !isAlnum(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isAlnum(r rune) bool {
+	return isDigit(r) || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+// shellSafeRegexp 不需要加引号的 shell 单词
+var shellSafeRegexp = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote 按照 POSIX sh 的规则为单词加引号，不含特殊字符时原样返回，
+// 否则整体放在单引号中，内容中的单引号先结束引号再转义。以 scriptDirMarker 开头的路径转换为相对于脚本所在目录的路径
+func shellQuote(s string) string {
+	if rest, found := strings.CutPrefix(s, scriptDirMarker); found {
+		return `"` + scriptDirSubstitution + `"` + shellQuote(rest)
+	}
+	if secretMarkerRegexp.MatchString(s) {
+		return shellQuoteSecrets(s)
+	}
+	if shellSafeRegexp.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellDoubleQuoteReplacer 转义双引号中有特殊含义的字符
+var shellDoubleQuoteReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+
+// shellQuoteSecrets 为包含脱敏标记的单词加双引号，标记替换为 ${VAR}，执行时从环境变量中读取
+func shellQuoteSecrets(s string) string {
+	var quoted strings.Builder
+	quoted.WriteString(`"`)
+	last := 0
+	for _, match := range secretMarkerRegexp.FindAllStringSubmatchIndex(s, -1) {
+		quoted.WriteString(shellDoubleQuoteReplacer.Replace(s[last:match[0]]))
+		quoted.WriteString("${" + s[match[2]:match[3]] + "}")
+		last = match[1]
+	}
+	quoted.WriteString(shellDoubleQuoteReplacer.Replace(s[last:]))
+	quoted.WriteString(`"`)
+	return quoted.String()
+}
+
+// shellCommand 为每个参数加引号后用空格拼接
+func shellCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellComment 将换行替换为空格，避免内容跳出注释行
+func shellComment(s string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+}
@@ -0,0 +1,129 @@
+package dockercli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+// shellQuoteTests 需要加引号才能原样保留的值
+var shellQuoteTests = []string{
+	"plain",
+	"",
+	"hello world",
+	"$HOME ${PATH} $$ $1",
+	"it's",
+	`say "hi"`,
+	`both 'single' and "double"`,
+	"line1\nline2\n",
+	"tab\there",
+	`back\slash \n \\`,
+	"`date` $(id)",
+	"a && b; c | d & e > f < g",
+	"# not a comment",
+	"*.go ?x [ab] ~root",
+	"unicode 中文 ✓",
+	"'",
+	`"`,
+	"--flag=value",
+}
+
+func TestShellQuoteRoundTrip(t *testing.T) {
+	for _, value := range shellQuoteTests {
+		quoted := shellQuote(value)
+		commands, err := splitShellCommands("echo " + quoted + "\n")
+		if err != nil {
+			t.Errorf("shellQuote(%q) = %s: %v", value, quoted, err)
+			continue
+		}
+		if len(commands) != 1 || len(commands[0]) != 2 || commands[0][1].Value != value {
+			t.Errorf("shellQuote(%q) = %s, tokenized as %v", value, quoted, commands)
+		}
+	}
+}
+
+func TestShellQuoteSecrets(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"__REDACTED_DB_PASSWORD__", `"${DB_PASSWORD}"`},
+		{"DB_PASSWORD=__REDACTED_DB_PASSWORD__", `"DB_PASSWORD=${DB_PASSWORD}"`},
+		{"url=postgres://u:__REDACTED_PW__@db/$x", `"url=postgres://u:${PW}@db/\$x"`},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.value); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestBuildRunCommandRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		entrypoint []string
+		cmd        []string
+	}{
+		{"cmd only", nil, []string{"sh", "-c", "echo 'hi' && sleep 1; done"}},
+		{"single entrypoint", []string{"/docker-entrypoint.sh"}, []string{"nginx", "-g", "daemon off;"}},
+		{"multi-element entrypoint", []string{"/bin/sh", "-c", "exec \"$0\" \"$@\""}, []string{"postgres", "-c", "max_connections=200"}},
+		{"entrypoint with spaces", []string{"/opt/my app/run", "--config", "/etc/a b.conf"}, nil},
+	}
+	for _, tt := range tests {
+		for _, pretty := range []bool{false, true} {
+			web := testContainer("aaaaaaaaaaaa1111", "web")
+			for i, value := range shellQuoteTests {
+				web.Config.Env = append(web.Config.Env, "V"+strings.Repeat("X", i)+"="+value)
+			}
+			web.Config.Labels = map[string]string{"with space": "a b", "quote": `it's "q"`, "dollar": "$HOME", "multi": "l1\nl2"}
+			web.Config.Entrypoint = tt.entrypoint
+			web.Config.Cmd = tt.cmd
+			web.Config.WorkingDir = "/srv/my app"
+
+			graph, err := buildContainerGraph([]types.ContainerJSON{web})
+			if err != nil {
+				t.Fatal(err)
+			}
+			script := buildDockerRunCommand(web, graph, pretty, "")
+			commands, err := splitShellCommands(script)
+			if err != nil {
+				t.Fatalf("%s: %v\n%s", tt.name, err, script)
+			}
+			var runs [][]shellWord
+			for _, words := range commands {
+				if !strings.HasPrefix(words[0].Value, "#") {
+					runs = append(runs, words)
+				}
+			}
+			if len(runs) != 1 {
+				t.Fatalf("%s: got %d commands, want 1\n%s", tt.name, len(runs), script)
+			}
+			args, ok := dockerRunArgs(runs[0])
+			if !ok {
+				t.Fatalf("%s: not a docker run command\n%s", tt.name, script)
+			}
+			spec, err := parseDockerRun(args, t.TempDir())
+			if err != nil {
+				t.Fatalf("%s: %v\n%s", tt.name, err, script)
+			}
+
+			if got, want := spec.Config.Env, web.Config.Env[1:]; !reflect.DeepEqual(got, want) {
+				t.Errorf("%s (pretty=%v): env = %q, want %q", tt.name, pretty, got, want)
+			}
+			if !reflect.DeepEqual(spec.Config.Labels, web.Config.Labels) {
+				t.Errorf("%s (pretty=%v): labels = %q, want %q", tt.name, pretty, spec.Config.Labels, web.Config.Labels)
+			}
+			if spec.Config.WorkingDir != web.Config.WorkingDir {
+				t.Errorf("%s (pretty=%v): workdir = %q", tt.name, pretty, spec.Config.WorkingDir)
+			}
+			// 多个元素的 ENTRYPOINT 拆分为 --entrypoint 和镜像之后的参数，实际执行的 argv 不变
+			want := append(append([]string{}, tt.entrypoint...), tt.cmd...)
+			got := append(append([]string{}, spec.Config.Entrypoint...), spec.Config.Cmd...)
+			if len(want) > 0 && !reflect.DeepEqual(got, want) {
+				t.Errorf("%s (pretty=%v): argv = %q, want %q\n%s", tt.name, pretty, got, want, script)
+			}
+		}
+	}
+}
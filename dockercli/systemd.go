@@ -0,0 +1,133 @@
+package dockercli
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+// Containers2Systemd 为每个容器生成 systemd 服务，服务在前台运行 docker run，由 systemd 负责重启
+func Containers2Systemd(containersJSON []types.ContainerJSON, pretty bool) (Files, error) {
+	graph, err := buildContainerGraph(containersJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(Files)
+	for _, containerJSON := range containersJSON {
+		cname := strings.TrimPrefix(containerJSON.Name, "/")
+		files[cname+".service"] = generateSystemdService(containerJSON, graph, pretty)
+	}
+	return files, nil
+}
+
+// generateSystemdService 生成单个容器的 .service 文件
+func generateSystemdService(containerJSON types.ContainerJSON, graph *containerGraph, pretty bool) string {
+	var unit strings.Builder
+	cname := strings.TrimPrefix(containerJSON.Name, "/")
+	sep := " "
+	if pretty {
+		sep = " \\\n  "
+	}
+
+	// [Unit]
+	unit.WriteString("[Unit]\n")
+	description := containerJSON.Config.Labels["description"]
+	if description == "" {
+		description = "Container " + cname
+	}
+	unit.WriteString(fmt.Sprintf("Description=%s\n", systemdComment(description)))
+	unit.WriteString("Requires=docker.service\n")
+	unit.WriteString("After=docker.service\n")
+	// 被 link、volumes-from 或 container: 引用的容器先启动
+	for _, dep := range graph.dependencies(cname) {
+		unit.WriteString(fmt.Sprintf("Requires=%s.service\n", dep))
+		unit.WriteString(fmt.Sprintf("After=%s.service\n", dep))
+	}
+
+	// [Service]
+	command := buildRunCommand(containerJSON, graph, "")
+	// 由 systemd 负责重启，docker 的重启策略会与之冲突
+	var options [][]string
+	for _, option := range command.Options {
+		if option[0] != "--restart" {
+			options = append(options, option)
+		}
+	}
+	command.Options = options
+
+	unit.WriteString("\n[Service]\n")
+	// 删除上次运行残留的同名容器，容器不存在时忽略错误
+	unit.WriteString(fmt.Sprintf("ExecStartPre=-/usr/bin/docker rm -f %s\n", systemdQuote(cname)))
+	if networks := extraNetworks(containerJSON); len(networks) > 0 {
+		// 连接多个网络时先创建容器，连接其余网络之后在前台启动
+		unit.WriteString("ExecStartPre=/usr/bin/docker create" + sep + command.render(systemdQuote, sep) + "\n")
+		for _, name := range networks {
+			unit.WriteString("ExecStartPre=/usr/bin/docker network connect " + systemdCommand(networkConnectArgs(containerJSON, name)) + "\n")
+		}
+		unit.WriteString(fmt.Sprintf("ExecStart=/usr/bin/docker start -a %s\n", systemdQuote(cname)))
+	} else {
+		unit.WriteString("ExecStart=/usr/bin/docker run" + sep + command.render(systemdQuote, sep) + "\n")
+	}
+	unit.WriteString(fmt.Sprintf("ExecStop=/usr/bin/docker stop %s\n", systemdQuote(cname)))
+	restart := systemdRestart(containerJSON.HostConfig.RestartPolicy)
+	if restart == "" {
+		restart = "no"
+	}
+	unit.WriteString(fmt.Sprintf("Restart=%s\n", restart))
+
+	// [Install]
+	unit.WriteString("\n[Install]\n")
+	unit.WriteString("WantedBy=multi-user.target\n")
+
+	return unit.String()
+}
+
+// systemdRestart 将 docker 的重启策略转换为 systemd 的 Restart=，不需要重启时返回空字符串
+func systemdRestart(policy container.RestartPolicy) string {
+	switch policy.Name {
+	case container.RestartPolicyAlways, container.RestartPolicyUnlessStopped:
+		return "always"
+	case container.RestartPolicyOnFailure:
+		return "on-failure"
+	}
+	return ""
+}
+
+// systemdSafeRegexp 不需要加引号的 systemd 单词
+var systemdSafeRegexp = regexp.MustCompile(`^[A-Za-z0-9_@+=:,./-]+$`)
+
+// systemdQuote 按照 systemd 单元文件的规则为单词加引号，
+// % 和 $ 分别写成 %% 和 $$，避免被当作说明符和环境变量展开
+func systemdQuote(s string) string {
+	if systemdSafeRegexp.MatchString(s) {
+		return s
+	}
+	s = strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"\n", `\n`,
+		"\t", `\t`,
+		"\r", `\r`,
+		"%", "%%",
+		"$", "$$",
+	).Replace(s)
+	return `"` + s + `"`
+}
+
+// systemdCommand 为每个参数加引号后用空格拼接
+func systemdCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = systemdQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// systemdComment 用于 Description= 等不按单词解析的值，去掉换行并转义 %
+func systemdComment(s string) string {
+	return strings.ReplaceAll(shellComment(s), "%", "%%")
+}
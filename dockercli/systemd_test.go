@@ -0,0 +1,94 @@
+package dockercli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestSystemdRestart(t *testing.T) {
+	tests := []struct {
+		policy container.RestartPolicyMode
+		want   string
+	}{
+		{container.RestartPolicyAlways, "always"},
+		{container.RestartPolicyUnlessStopped, "always"},
+		{container.RestartPolicyOnFailure, "on-failure"},
+		{container.RestartPolicyDisabled, ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := systemdRestart(container.RestartPolicy{Name: tt.policy}); got != tt.want {
+			t.Errorf("systemdRestart(%q) = %q, want %q", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestSystemdQuote(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"nginx:latest", "nginx:latest"},
+		{"KEY=value", "KEY=value"},
+		{"hello world", `"hello world"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"$HOME 100%", `"$$HOME 100%%"`},
+		{"line1\nline2", `"line1\nline2"`},
+		{`C:\path`, `"C:\\path"`},
+	}
+	for _, tt := range tests {
+		if got := systemdQuote(tt.s); got != tt.want {
+			t.Errorf("systemdQuote(%q) = %s, want %s", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestSystemdService(t *testing.T) {
+	db := testContainer("bbbbbbbbbbbb2222", "db")
+	db.HostConfig.RestartPolicy = container.RestartPolicy{Name: container.RestartPolicyOnFailure, MaximumRetryCount: 3}
+	web := multiNetworkContainer()
+	web.HostConfig.RestartPolicy = container.RestartPolicy{Name: container.RestartPolicyAlways}
+	web.HostConfig.VolumesFrom = []string{"db"}
+	web.Config.Env = append(web.Config.Env, "GREETING=hello $USER")
+
+	dump, err := ParseContainers([]types.ContainerJSON{web, db}, &ExportOptions{Format: "systemd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := dump.(Files)
+
+	unit := files["web.service"]
+	for _, want := range []string{
+		"Requires=docker.service\nAfter=docker.service\nRequires=db.service\nAfter=db.service\n",
+		"ExecStartPre=-/usr/bin/docker rm -f web\n",
+		// 连接多个网络时先创建容器，再连接其余网络
+		"ExecStartPre=/usr/bin/docker create --name web -e \"GREETING=hello $$USER\" --volumes-from db --network app",
+		"ExecStartPre=/usr/bin/docker network connect --alias api --ip 172.21.0.10 --link db:database backend web\n",
+		"ExecStart=/usr/bin/docker start -a web\n",
+		"ExecStop=/usr/bin/docker stop web\nRestart=always\n",
+		"[Install]\nWantedBy=multi-user.target\n",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("missing %q in:\n%s", want, unit)
+		}
+	}
+	// 由 systemd 负责重启，docker run 中不保留 --restart
+	if strings.Contains(unit, "--restart") {
+		t.Errorf("unexpected --restart in:\n%s", unit)
+	}
+
+	unit = files["db.service"]
+	for _, want := range []string{
+		"ExecStart=/usr/bin/docker run --name db nginx:latest\n",
+		"Restart=on-failure\n",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("missing %q in:\n%s", want, unit)
+		}
+	}
+	if strings.Contains(unit, " -d ") {
+		t.Errorf("the service must run the container in the foreground:\n%s", unit)
+	}
+}
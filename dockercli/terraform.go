@@ -0,0 +1,424 @@
+package dockercli
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/fimreal/goutils/ezap"
+)
+
+// terraformFileName 导出的 terraform 文件名
+const terraformFileName = "main.tf"
+
+// tfInvalidNameRegexp terraform 资源名称中不允许的字符
+var tfInvalidNameRegexp = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// tfName 将容器、镜像等名称转换为合法的 terraform 资源名称
+func tfName(name string) string {
+	name = tfInvalidNameRegexp.ReplaceAllString(name, "_")
+	if name == "" || isDigit(rune(name[0])) || name[0] == '-' {
+		name = "_" + name
+	}
+	return name
+}
+
+// Containers2Terraform 生成 kreuzwerker/docker provider 的 docker_image、docker_network、
+// docker_volume 和 docker_container 资源，资源之间通过引用关联。
+// opts.TerraformImport 为 true 时为已存在的容器、网络和卷生成 import 块，无需重新创建即可纳入 terraform 管理
+func Containers2Terraform(containersJSON []types.ContainerJSON, opts *ExportOptions) (Files, error) {
+	graph, err := buildContainerGraph(containersJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	var tf strings.Builder
+	tf.WriteString("terraform {\n")
+	tf.WriteString("  required_providers {\n")
+	tf.WriteString("    docker = {\n")
+	tf.WriteString("      source  = \"kreuzwerker/docker\"\n")
+	tf.WriteString("      version = \"~> 3.0\"\n")
+	tf.WriteString("    }\n")
+	tf.WriteString("  }\n")
+	tf.WriteString("}\n")
+
+	// images
+	seen := make(map[string]bool)
+	for _, containerJSON := range containersJSON {
+		image := containerJSON.Config.Image
+		if seen[image] {
+			continue
+		}
+		seen[image] = true
+		tf.WriteString(fmt.Sprintf("\nresource \"docker_image\" %s {\n", hclQuote(tfName(image))))
+		tf.WriteString(fmt.Sprintf("  name         = %s\n", hclQuote(image)))
+		// 销毁资源时不删除本地镜像
+		tf.WriteString("  keep_locally = true\n")
+		tf.WriteString("}\n")
+	}
+
+	// networks
+	seen = make(map[string]bool)
+	for _, containerJSON := range containersJSON {
+		for _, name := range userNetworks(containerJSON) {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			tf.WriteString(fmt.Sprintf("\nresource \"docker_network\" %s {\n", hclQuote(tfName(name))))
+			tf.WriteString(fmt.Sprintf("  name = %s\n", hclQuote(name)))
+			networkJSON, ok := opts.Networks[name]
+			if ok {
+				if networkJSON.Driver != "" {
+					tf.WriteString(fmt.Sprintf("  driver = %s\n", hclQuote(networkJSON.Driver)))
+				}
+				if networkJSON.Internal {
+					tf.WriteString("  internal = true\n")
+				}
+				if networkJSON.Attachable {
+					tf.WriteString("  attachable = true\n")
+				}
+				if networkJSON.EnableIPv6 {
+					tf.WriteString("  ipv6 = true\n")
+				}
+				if networkJSON.IPAM.Driver != "" && networkJSON.IPAM.Driver != "default" {
+					tf.WriteString(fmt.Sprintf("  ipam_driver = %s\n", hclQuote(networkJSON.IPAM.Driver)))
+				}
+				writeHCLMap(&tf, "  ipam_options", networkJSON.IPAM.Options)
+				writeHCLMap(&tf, "  options", networkJSON.Options)
+				for _, ipam := range networkJSON.IPAM.Config {
+					tf.WriteString("\n  ipam_config {\n")
+					if ipam.Subnet != "" {
+						tf.WriteString(fmt.Sprintf("    subnet = %s\n", hclQuote(ipam.Subnet)))
+					}
+					if ipam.Gateway != "" {
+						tf.WriteString(fmt.Sprintf("    gateway = %s\n", hclQuote(ipam.Gateway)))
+					}
+					if ipam.IPRange != "" {
+						tf.WriteString(fmt.Sprintf("    ip_range = %s\n", hclQuote(ipam.IPRange)))
+					}
+					tf.WriteString("  }\n")
+				}
+				writeTerraformLabels(&tf, userLabels(networkJSON.Labels))
+			}
+			tf.WriteString("}\n")
+			if opts.TerraformImport && ok && networkJSON.ID != "" {
+				writeTerraformImport(&tf, "docker_network."+tfName(name), networkJSON.ID)
+			}
+		}
+	}
+
+	// volumes
+	for _, mount := range referencedVolumes(containersJSON) {
+		volumeJSON := volumeDefinition(mount, opts.Volumes)
+		tf.WriteString(fmt.Sprintf("\nresource \"docker_volume\" %s {\n", hclQuote(tfName(mount.Name))))
+		tf.WriteString(fmt.Sprintf("  name = %s\n", hclQuote(mount.Name)))
+		if volumeJSON.Driver != "" {
+			tf.WriteString(fmt.Sprintf("  driver = %s\n", hclQuote(volumeJSON.Driver)))
+		}
+		writeHCLMap(&tf, "  driver_opts", volumeJSON.Options)
+		writeTerraformLabels(&tf, userLabels(volumeJSON.Labels))
+		tf.WriteString("}\n")
+		if opts.TerraformImport {
+			// 卷的 ID 即名称
+			writeTerraformImport(&tf, "docker_volume."+tfName(mount.Name), mount.Name)
+		}
+	}
+
+	// containers
+	for _, containerJSON := range graph.sortContainers(containersJSON) {
+		tf.WriteString("\n")
+		tf.WriteString(generateTerraformContainer(containerJSON, graph))
+		if opts.TerraformImport {
+			cname := strings.TrimPrefix(containerJSON.Name, "/")
+			writeTerraformImport(&tf, "docker_container."+tfName(cname), containerJSON.ID)
+		}
+	}
+
+	return Files{terraformFileName: tf.String()}, nil
+}
+
+// generateTerraformContainer 生成单个容器的 docker_container 资源
+func generateTerraformContainer(containerJSON types.ContainerJSON, graph *containerGraph) string {
+	var resource strings.Builder
+	cname := strings.TrimPrefix(containerJSON.Name, "/")
+	config := containerJSON.Config
+	hostConfig := containerJSON.HostConfig
+
+	resource.WriteString(fmt.Sprintf("resource \"docker_container\" %s {\n", hclQuote(tfName(cname))))
+	resource.WriteString(fmt.Sprintf("  name  = %s\n", hclQuote(cname)))
+	resource.WriteString(fmt.Sprintf("  image = docker_image.%s.image_id\n", tfName(config.Image)))
+
+	// hostname
+	if hostname := customHostname(containerJSON); hostname != "" {
+		resource.WriteString(fmt.Sprintf("  hostname = %s\n", hclQuote(hostname)))
+	}
+
+	// restart policy
+	if hostConfig.RestartPolicy.Name != "" {
+		resource.WriteString(fmt.Sprintf("  restart = %s\n", hclQuote(string(hostConfig.RestartPolicy.Name))))
+		if hostConfig.RestartPolicy.MaximumRetryCount > 0 {
+			resource.WriteString(fmt.Sprintf("  max_retry_count = %d\n", hostConfig.RestartPolicy.MaximumRetryCount))
+		}
+	}
+
+	// user
+	if config.User != "" {
+		resource.WriteString(fmt.Sprintf("  user = %s\n", hclQuote(config.User)))
+	}
+
+	// workdir
+	if config.WorkingDir != "" {
+		resource.WriteString(fmt.Sprintf("  working_dir = %s\n", hclQuote(config.WorkingDir)))
+	}
+
+	// entrypoint
+	if len(config.Entrypoint) > 0 {
+		resource.WriteString(fmt.Sprintf("  entrypoint = %s\n", hclList(config.Entrypoint)))
+	}
+
+	// command
+	if len(config.Cmd) > 0 {
+		resource.WriteString(fmt.Sprintf("  command = %s\n", hclList(config.Cmd)))
+	}
+
+	// environment variables
+	var env []string
+	for _, e := range config.Env {
+		if e == "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin" {
+			continue
+		}
+		env = append(env, e)
+	}
+	if len(env) > 0 {
+		resource.WriteString(fmt.Sprintf("  env = %s\n", hclList(env)))
+	}
+
+	// privileged mode
+	if hostConfig.Privileged {
+		resource.WriteString("  privileged = true\n")
+	}
+
+	// readonly root fs
+	if hostConfig.ReadonlyRootfs {
+		resource.WriteString("  read_only = true\n")
+	}
+
+	// user namespace mode
+	if hostConfig.UsernsMode != "" {
+		resource.WriteString(fmt.Sprintf("  userns_mode = %s\n", hclQuote(string(hostConfig.UsernsMode))))
+	}
+
+	// pid mode
+	if ref, ok := graph.ref(cname, refPid); ok {
+		resource.WriteString(fmt.Sprintf("  pid_mode = \"container:${docker_container.%s.name}\"\n", tfName(ref.Target)))
+	} else if hostConfig.PidMode != "" {
+		resource.WriteString(fmt.Sprintf("  pid_mode = %s\n", hclQuote(string(hostConfig.PidMode))))
+	}
+
+	// ipc mode
+	if ref, ok := graph.ref(cname, refIpc); ok {
+		resource.WriteString(fmt.Sprintf("  ipc_mode = \"container:${docker_container.%s.name}\"\n", tfName(ref.Target)))
+	} else if ipcMode := hostConfig.IpcMode; ipcMode.IsHost() || ipcMode.IsNone() {
+		resource.WriteString(fmt.Sprintf("  ipc_mode = %s\n", hclQuote(string(ipcMode))))
+	}
+
+	// link
+	if refs := graph.refsOf(cname, refLink); len(refs) > 0 {
+		ezap.Warnf("%s: links are not supported by the terraform docker provider, use a user-defined network instead", cname)
+	}
+
+	// cpu shares
+	if hostConfig.CPUShares > 0 {
+		resource.WriteString(fmt.Sprintf("  cpu_shares = %d\n", hostConfig.CPUShares))
+	}
+
+	// cpuset
+	if hostConfig.CpusetCpus != "" {
+		resource.WriteString(fmt.Sprintf("  cpu_set = %s\n", hclQuote(hostConfig.CpusetCpus)))
+	}
+
+	// memory limit，单位为 MB
+	if hostConfig.Memory > 0 {
+		resource.WriteString(fmt.Sprintf("  memory = %d\n", max(hostConfig.Memory>>20, 1)))
+	}
+	if hostConfig.MemorySwap == -1 || hostConfig.MemorySwap > 0 {
+		swap := hostConfig.MemorySwap
+		if swap > 0 {
+			swap = max(swap>>20, 1)
+		}
+		resource.WriteString(fmt.Sprintf("  memory_swap = %d\n", swap))
+	}
+
+	// 其余资源限制 terraform docker provider 不支持，--cpus 可以改用 cpu_shares 或 cpu_set
+	resources := *hostConfig
+	resources.CPUShares, resources.CpusetCpus, resources.Memory, resources.MemorySwap = 0, "", 0, 0
+	for _, option := range resourceOptions(&resources) {
+		ezap.Warnf("%s: %s is not supported by the terraform docker provider, dropped", cname, option[0])
+	}
+
+	// network mode
+	if ref, ok := graph.ref(cname, refNetwork); ok {
+		resource.WriteString(fmt.Sprintf("  network_mode = \"container:${docker_container.%s.name}\"\n", tfName(ref.Target)))
+	} else if mode := hostConfig.NetworkMode; mode.IsHost() || mode.IsNone() {
+		resource.WriteString(fmt.Sprintf("  network_mode = %s\n", hclQuote(string(mode))))
+	}
+
+	// dns
+	if len(hostConfig.DNS) > 0 {
+		resource.WriteString(fmt.Sprintf("  dns = %s\n", hclList(hostConfig.DNS)))
+	}
+
+	// log driver
+	if hostConfig.LogConfig.Type != "" && hostConfig.LogConfig.Type != "json-file" {
+		resource.WriteString(fmt.Sprintf("  log_driver = %s\n", hclQuote(hostConfig.LogConfig.Type)))
+	}
+	writeHCLMap(&resource, "  log_opts", hostConfig.LogConfig.Config)
+
+	// capabilities
+	if len(hostConfig.CapAdd) > 0 || len(hostConfig.CapDrop) > 0 {
+		resource.WriteString("\n  capabilities {\n")
+		if len(hostConfig.CapAdd) > 0 {
+			resource.WriteString(fmt.Sprintf("    add  = %s\n", hclList(hostConfig.CapAdd)))
+		}
+		if len(hostConfig.CapDrop) > 0 {
+			resource.WriteString(fmt.Sprintf("    drop = %s\n", hclList(hostConfig.CapDrop)))
+		}
+		resource.WriteString("  }\n")
+	}
+
+	// host-to-IP mapping
+	for _, alias := range hostAliases(hostConfig.ExtraHosts) {
+		for _, hostname := range alias.hostnames {
+			resource.WriteString("\n  host {\n")
+			resource.WriteString(fmt.Sprintf("    host = %s\n", hclQuote(hostname)))
+			resource.WriteString(fmt.Sprintf("    ip   = %s\n", hclQuote(alias.ip)))
+			resource.WriteString("  }\n")
+		}
+	}
+
+	// networks，provider 不支持为每个网络指定 MAC 地址
+	for _, name := range userNetworks(containerJSON) {
+		resource.WriteString("\n  networks_advanced {\n")
+		resource.WriteString(fmt.Sprintf("    name = docker_network.%s.name\n", tfName(name)))
+		if endpoint := networkEndpoint(containerJSON, name); endpoint != nil {
+			if aliases := endpointAliases(containerJSON, endpoint); len(aliases) > 0 {
+				resource.WriteString(fmt.Sprintf("    aliases = %s\n", hclList(aliases)))
+			}
+			if ipam := endpoint.IPAMConfig; ipam != nil {
+				if ipam.IPv4Address != "" {
+					resource.WriteString(fmt.Sprintf("    ipv4_address = %s\n", hclQuote(ipam.IPv4Address)))
+				}
+				if ipam.IPv6Address != "" {
+					resource.WriteString(fmt.Sprintf("    ipv6_address = %s\n", hclQuote(ipam.IPv6Address)))
+				}
+			}
+			if customMacAddress(endpoint) != "" {
+				ezap.Warnf("%s: the terraform docker provider does not support a mac address per network, the mac address on network %s is dropped", cname, name)
+			}
+		}
+		resource.WriteString("  }\n")
+	}
+
+	// port mapping
+	added := make(map[string]bool)
+	for _, port := range containerPorts(containerJSON) {
+		for _, binding := range containerJSON.NetworkSettings.Ports[port] {
+			portMapping := portSpec(port, binding)
+			if added[portMapping] {
+				continue
+			}
+			added[portMapping] = true
+			resource.WriteString("\n  ports {\n")
+			resource.WriteString(fmt.Sprintf("    internal = %d\n", port.Int()))
+			if binding.HostPort != "" {
+				resource.WriteString(fmt.Sprintf("    external = %s\n", binding.HostPort))
+			}
+			if binding.HostIP != "" {
+				resource.WriteString(fmt.Sprintf("    ip       = %s\n", hclQuote(binding.HostIP)))
+			}
+			if port.Proto() != "tcp" {
+				resource.WriteString(fmt.Sprintf("    protocol = %s\n", hclQuote(port.Proto())))
+			}
+			resource.WriteString("  }\n")
+		}
+	}
+
+	// mount
+	for _, mount := range containerJSON.Mounts {
+		var source string
+		switch {
+		case mount.Type == "bind":
+			source = fmt.Sprintf("    host_path      = %s\n", hclQuote(mount.Source))
+		case mount.Type == "volume" && mount.Name != "" && !isAnonymousVolume(mount.Name):
+			source = fmt.Sprintf("    volume_name    = docker_volume.%s.name\n", tfName(mount.Name))
+		case mount.Type == "volume":
+			// 匿名卷只指定容器内路径
+		default:
+			continue
+		}
+		resource.WriteString("\n  volumes {\n")
+		resource.WriteString(source)
+		resource.WriteString(fmt.Sprintf("    container_path = %s\n", hclQuote(mount.Destination)))
+		if !mount.RW {
+			resource.WriteString("    read_only      = true\n")
+		}
+		resource.WriteString("  }\n")
+	}
+
+	// volumes from
+	for _, ref := range graph.refsOf(cname, refVolumesFrom) {
+		resource.WriteString("\n  volumes {\n")
+		resource.WriteString(fmt.Sprintf("    from_container = docker_container.%s.name\n", tfName(ref.Target)))
+		if ref.Option == "ro" {
+			resource.WriteString("    read_only      = true\n")
+		}
+		resource.WriteString("  }\n")
+	}
+
+	// devices
+	for _, device := range hostConfig.Devices {
+		resource.WriteString("\n  devices {\n")
+		resource.WriteString(fmt.Sprintf("    host_path      = %s\n", hclQuote(device.PathOnHost)))
+		resource.WriteString(fmt.Sprintf("    container_path = %s\n", hclQuote(device.PathInContainer)))
+		resource.WriteString("  }\n")
+	}
+
+	// label
+	writeTerraformLabels(&resource, config.Labels)
+
+	resource.WriteString("}\n")
+	return resource.String()
+}
+
+// writeTerraformImport 写入 import 块
+func writeTerraformImport(builder *strings.Builder, to, id string) {
+	builder.WriteString("\nimport {\n")
+	builder.WriteString(fmt.Sprintf("  to = %s\n", to))
+	builder.WriteString(fmt.Sprintf("  id = %s\n", hclQuote(id)))
+	builder.WriteString("}\n")
+}
+
+// writeTerraformLabels 按照键排序写入 labels 块
+func writeTerraformLabels(builder *strings.Builder, labels map[string]string) {
+	for _, key := range sortedKeys(labels) {
+		builder.WriteString("\n  labels {\n")
+		builder.WriteString(fmt.Sprintf("    label = %s\n", hclQuote(key)))
+		builder.WriteString(fmt.Sprintf("    value = %s\n", hclQuote(labels[key])))
+		builder.WriteString("  }\n")
+	}
+}
+
+// writeHCLMap 按照键排序写入 HCL 的 map 属性，空映射不输出
+func writeHCLMap(builder *strings.Builder, key string, m map[string]string) {
+	if len(m) == 0 {
+		return
+	}
+	indent := strings.Repeat(" ", len(key)-len(strings.TrimLeft(key, " ")))
+	builder.WriteString(key + " = {\n")
+	for _, k := range sortedKeys(m) {
+		builder.WriteString(fmt.Sprintf("%s  %s = %s\n", indent, hclQuote(k), hclQuote(m[k])))
+	}
+	builder.WriteString(indent + "}\n")
+}
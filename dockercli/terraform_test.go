@@ -0,0 +1,85 @@
+package dockercli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+)
+
+func TestTfName(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"web", "web"},
+		{"my-app_1", "my-app_1"},
+		{"nginx:latest", "nginx_latest"},
+		{"registry.example.com/team/app:1.0", "registry_example_com_team_app_1_0"},
+		{"1web", "_1web"},
+		{"-web", "_-web"},
+	}
+	for _, tt := range tests {
+		if got := tfName(tt.name); got != tt.want {
+			t.Errorf("tfName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHCLQuote(t *testing.T) {
+	// ${ 和 %{ 不能被 terraform 当作模板展开
+	if got, want := hclQuote(`${HOME} %{if} "x"`), `"$${HOME} %%{if} \"x\""`; got != want {
+		t.Errorf("hclQuote = %s, want %s", got, want)
+	}
+}
+
+func TestTerraformResources(t *testing.T) {
+	db := volumeContainer()
+	db.HostConfig.RestartPolicy = container.RestartPolicy{Name: container.RestartPolicyOnFailure, MaximumRetryCount: 3}
+	web := multiNetworkContainer()
+	web.Config.Image = "registry.example.com/web:1.0"
+	web.HostConfig.VolumesFrom = []string{"db:ro"}
+	web.HostConfig.PidMode = "container:bbbbbbbbbbbb2222"
+	web.Config.Env = append(web.Config.Env, "GREETING=${USER}")
+
+	opts := &ExportOptions{
+		Format:          "terraform",
+		TerraformImport: true,
+		Networks:        map[string]network.Inspect{"app": appNetwork()},
+		Volumes:         map[string]volume.Volume{"dbdata": dbdataVolume()},
+	}
+	tf := exportString(t, []types.ContainerJSON{web, db}, opts)
+	for _, want := range []string{
+		"      source  = \"kreuzwerker/docker\"\n",
+		"resource \"docker_image\" \"registry_example_com_web_1_0\" {\n  name         = \"registry.example.com/web:1.0\"\n  keep_locally = true\n}\n",
+		"resource \"docker_image\" \"nginx_latest\" {\n",
+		"import {\n  to = docker_network.app\n  id = \"0123456789abcdef\"\n}\n",
+		"import {\n  to = docker_volume.dbdata\n  id = \"dbdata\"\n}\n",
+		"resource \"docker_container\" \"db\" {\n  name  = \"db\"\n  image = docker_image.nginx_latest.image_id\n  restart = \"on-failure\"\n  max_retry_count = 3\n",
+		"import {\n  to = docker_container.db\n  id = \"bbbbbbbbbbbb2222\"\n}\n",
+		"resource \"docker_container\" \"web\" {\n  name  = \"web\"\n  image = docker_image.registry_example_com_web_1_0.image_id\n",
+		"  env = [\"GREETING=$${USER}\"]\n",
+		"  pid_mode = \"container:${docker_container.db.name}\"\n",
+		"  volumes {\n    from_container = docker_container.db.name\n    read_only      = true\n  }\n",
+		"import {\n  to = docker_container.web\n  id = \"aaaaaaaaaaaa1111\"\n}\n",
+	} {
+		if !strings.Contains(tf, want) {
+			t.Errorf("missing %q in:\n%s", want, tf)
+		}
+	}
+	// 被引用的容器先定义
+	if strings.Index(tf, "resource \"docker_container\" \"db\"") > strings.Index(tf, "resource \"docker_container\" \"web\"") {
+		t.Errorf("db must be defined before web:\n%s", tf)
+	}
+	// 没有网络详细信息时不生成 import 块
+	if strings.Contains(tf, "docker_network.backend\n") {
+		t.Errorf("unexpected import of network backend:\n%s", tf)
+	}
+
+	opts.TerraformImport = false
+	if tf := exportString(t, []types.ContainerJSON{web, db}, opts); strings.Contains(tf, "import {") {
+		t.Errorf("unexpected import blocks:\n%s", tf)
+	}
+}
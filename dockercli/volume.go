@@ -0,0 +1,225 @@
+package dockercli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// referencedVolumes 返回容器挂载的命名卷，按名称排序并去重
+func referencedVolumes(containersJSON []types.ContainerJSON) []types.MountPoint {
+	var volumes []types.MountPoint
+	seen := make(map[string]bool)
+	for _, containerJSON := range containersJSON {
+		for _, mount := range namedVolumes(containerJSON) {
+			if !seen[mount.Name] {
+				seen[mount.Name] = true
+				volumes = append(volumes, mount)
+			}
+		}
+	}
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Name < volumes[j].Name })
+	return volumes
+}
+
+// volumeDefinition 返回命名卷的定义，没有通过 InspectVolumes 获取到时只包含挂载信息中的名称和驱动
+func volumeDefinition(mount types.MountPoint, volumes map[string]volume.Volume) volume.Volume {
+	if volumeJSON, ok := volumes[mount.Name]; ok {
+		return volumeJSON
+	}
+	return volume.Volume{Name: mount.Name, Driver: mount.Driver}
+}
+
+// volumeCreateArgs 返回重新创建卷的 docker volume create 参数，均未加引号
+func volumeCreateArgs(volumeJSON volume.Volume) []string {
+	var args []string
+	if volumeJSON.Driver != "" && volumeJSON.Driver != "local" {
+		args = append(args, "--driver", volumeJSON.Driver)
+	}
+	for _, key := range sortedKeys(volumeJSON.Options) {
+		args = append(args, "--opt", key+"="+volumeJSON.Options[key])
+	}
+	labels := userLabels(volumeJSON.Labels)
+	for _, key := range sortedKeys(labels) {
+		args = append(args, "--label", key+"="+labels[key])
+	}
+	return append(args, volumeJSON.Name)
+}
+
+// hostMount 返回通过 --mount 指定的挂载配置，通过 -v 指定或镜像中定义的卷返回 nil
+func hostMount(containerJSON types.ContainerJSON, target string) *mount.Mount {
+	for i := range containerJSON.HostConfig.Mounts {
+		if containerJSON.HostConfig.Mounts[i].Target == target {
+			return &containerJSON.HostConfig.Mounts[i]
+		}
+	}
+	return nil
+}
+
+// mountSource 返回挂载的来源，命名卷使用卷名称而不是宿主机上的数据目录，匿名卷返回空字符串
+func mountSource(mountPoint types.MountPoint) string {
+	if mountPoint.Type == mount.TypeVolume && (mountPoint.Name == "" || isAnonymousVolume(mountPoint.Name)) {
+		return ""
+	}
+	if mountPoint.Type == mount.TypeVolume {
+		return mountPoint.Name
+	}
+	return mountPoint.Source
+}
+
+// shortMountOptions 返回 -v 参数中的挂载选项，例如 ro、z、rshared、nocopy。
+// Mode 中保存的是创建容器时指定的选项，只读和传播方式以 RW、Propagation 为准
+func shortMountOptions(mountPoint types.MountPoint) []string {
+	var options []string
+	if !mountPoint.RW {
+		options = append(options, "ro")
+	}
+	for _, option := range strings.Split(mountPoint.Mode, ",") {
+		switch {
+		case option == "z" || option == "Z":
+			options = append(options, option)
+		case option == "nocopy" && mountPoint.Type == mount.TypeVolume:
+			options = append(options, option)
+		}
+	}
+	// bind 挂载默认的传播方式为 rprivate
+	if mountPoint.Type == mount.TypeBind && mountPoint.Propagation != "" && mountPoint.Propagation != mount.PropagationRPrivate {
+		options = append(options, string(mountPoint.Propagation))
+	}
+	return options
+}
+
+// shortMountSpec 返回挂载对应的 -v 参数，格式为 [source:]target[:options]
+func shortMountSpec(mountPoint types.MountPoint) string {
+	spec := mountPoint.Destination
+	if source := mountSource(mountPoint); source != "" {
+		spec = source + ":" + spec
+	}
+	if options := shortMountOptions(mountPoint); len(options) > 0 {
+		spec += ":" + strings.Join(options, ",")
+	}
+	return spec
+}
+
+// mountSpec 返回 --mount 参数，包含只读、传播方式、卷驱动和 tmpfs 大小等选项
+func mountSpec(m mount.Mount) string {
+	fields := []string{"type=" + string(m.Type)}
+	if m.Source != "" {
+		fields = append(fields, "source="+m.Source)
+	}
+	fields = append(fields, "target="+m.Target)
+	if m.ReadOnly {
+		fields = append(fields, "readonly")
+	}
+	if m.Consistency != "" && m.Consistency != mount.ConsistencyDefault {
+		fields = append(fields, "consistency="+string(m.Consistency))
+	}
+	if bind := m.BindOptions; bind != nil {
+		if bind.Propagation != "" {
+			fields = append(fields, "bind-propagation="+string(bind.Propagation))
+		}
+		if bind.NonRecursive {
+			fields = append(fields, "bind-nonrecursive")
+		}
+	}
+	if volumeOptions := m.VolumeOptions; volumeOptions != nil {
+		if volumeOptions.NoCopy {
+			fields = append(fields, "volume-nocopy")
+		}
+		if volumeOptions.Subpath != "" {
+			fields = append(fields, "volume-subpath="+volumeOptions.Subpath)
+		}
+		if driver := volumeOptions.DriverConfig; driver != nil {
+			if driver.Name != "" {
+				fields = append(fields, "volume-driver="+driver.Name)
+			}
+			for _, key := range sortedKeys(driver.Options) {
+				fields = append(fields, "volume-opt="+key+"="+driver.Options[key])
+			}
+		}
+		for _, key := range sortedKeys(volumeOptions.Labels) {
+			fields = append(fields, "volume-label="+key+"="+volumeOptions.Labels[key])
+		}
+	}
+	if tmpfs := m.TmpfsOptions; tmpfs != nil {
+		if tmpfs.SizeBytes > 0 {
+			fields = append(fields, "tmpfs-size="+bytesSize(tmpfs.SizeBytes))
+		}
+		if tmpfs.Mode != 0 {
+			fields = append(fields, fmt.Sprintf("tmpfs-mode=%o", tmpfs.Mode))
+		}
+	}
+	return mountOptions(fields...)
+}
+
+// needsLongSyntax 判断 --mount 指定的挂载是否需要使用 compose 的长语法，
+// 只读和传播方式之外的选项无法用短语法表示
+func needsLongSyntax(m *mount.Mount) bool {
+	if m == nil {
+		return false
+	}
+	if m.Type != mount.TypeBind && m.Type != mount.TypeVolume {
+		return true
+	}
+	if m.Consistency != "" && m.Consistency != mount.ConsistencyDefault {
+		return true
+	}
+	if bind := m.BindOptions; bind != nil && (bind.NonRecursive || bind.CreateMountpoint) {
+		return true
+	}
+	if volumeOptions := m.VolumeOptions; volumeOptions != nil && (volumeOptions.NoCopy || volumeOptions.Subpath != "") {
+		return true
+	}
+	return m.TmpfsOptions != nil
+}
+
+// generateComposeMount 生成 compose 长语法的挂载配置
+func generateComposeMount(m mount.Mount) string {
+	var entry strings.Builder
+	entry.WriteString(fmt.Sprintf("      - type: %s\n", composeScalar(string(m.Type))))
+	if m.Source != "" {
+		entry.WriteString(fmt.Sprintf("        source: %s\n", composeScalar(m.Source)))
+	}
+	entry.WriteString(fmt.Sprintf("        target: %s\n", composeScalar(m.Target)))
+	if m.ReadOnly {
+		entry.WriteString("        read_only: true\n")
+	}
+	if m.Consistency != "" && m.Consistency != mount.ConsistencyDefault {
+		entry.WriteString(fmt.Sprintf("        consistency: %s\n", composeScalar(string(m.Consistency))))
+	}
+	if bind := m.BindOptions; bind != nil && (bind.Propagation != "" || bind.NonRecursive || bind.CreateMountpoint) {
+		entry.WriteString("        bind:\n")
+		if bind.Propagation != "" {
+			entry.WriteString(fmt.Sprintf("          propagation: %s\n", composeScalar(string(bind.Propagation))))
+		}
+		if bind.NonRecursive {
+			entry.WriteString("          recursive: disabled\n")
+		}
+		if bind.CreateMountpoint {
+			entry.WriteString("          create_host_path: true\n")
+		}
+	}
+	if volumeOptions := m.VolumeOptions; volumeOptions != nil && (volumeOptions.NoCopy || volumeOptions.Subpath != "") {
+		entry.WriteString("        volume:\n")
+		if volumeOptions.NoCopy {
+			entry.WriteString("          nocopy: true\n")
+		}
+		if volumeOptions.Subpath != "" {
+			entry.WriteString(fmt.Sprintf("          subpath: %s\n", composeScalar(volumeOptions.Subpath)))
+		}
+	}
+	if tmpfs := m.TmpfsOptions; tmpfs != nil && (tmpfs.SizeBytes > 0 || tmpfs.Mode != 0) {
+		entry.WriteString("        tmpfs:\n")
+		if tmpfs.SizeBytes > 0 {
+			entry.WriteString(fmt.Sprintf("          size: %s\n", bytesSize(tmpfs.SizeBytes)))
+		}
+		if tmpfs.Mode != 0 {
+			entry.WriteString(fmt.Sprintf("          mode: 0o%o\n", tmpfs.Mode))
+		}
+	}
+	return entry.String()
+}
@@ -0,0 +1,184 @@
+package dockercli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// anonymousVolume 匿名卷的名称
+const anonymousVolume = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+// volumeContainer 返回挂载命名卷和匿名卷的容器
+func volumeContainer() types.ContainerJSON {
+	db := testContainer("bbbbbbbbbbbb2222", "db")
+	db.Mounts = []types.MountPoint{
+		{Type: "volume", Name: "dbdata", Source: "/var/lib/docker/volumes/dbdata/_data", Destination: "/var/lib/db", Driver: "local", RW: true},
+		{Type: "volume", Name: anonymousVolume, Source: "/var/lib/docker/volumes/" + anonymousVolume + "/_data", Destination: "/cache", Driver: "local", RW: true},
+	}
+	return db
+}
+
+// dbdataVolume 返回 VolumeInspect 结果中的命名卷，包含 compose 自动添加的标签
+func dbdataVolume() volume.Volume {
+	return volume.Volume{
+		Name:    "dbdata",
+		Driver:  "local",
+		Options: map[string]string{"type": "nfs", "o": "addr=10.0.0.1", "device": ":/data"},
+		Labels:  map[string]string{"com.docker.compose.volume": "dbdata", "backup": "daily"},
+	}
+}
+
+func TestVolumeCreateArgs(t *testing.T) {
+	want := []string{"--opt", "device=:/data", "--opt", "o=addr=10.0.0.1", "--opt", "type=nfs", "--label", "backup=daily", "dbdata"}
+	if got := volumeCreateArgs(dbdataVolume()); !reflect.DeepEqual(got, want) {
+		t.Errorf("args = %q\nwant %q", got, want)
+	}
+	plugin := volume.Volume{Name: "shared", Driver: "rexray"}
+	if got, want := volumeCreateArgs(plugin), []string{"--driver", "rexray", "shared"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+}
+
+func TestNamedVolumesExport(t *testing.T) {
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"command", []string{
+			"# Volume: dbdata\ndocker volume create --opt device=:/data --opt o=addr=10.0.0.1 --opt type=nfs --label backup=daily dbdata\n",
+			"-v dbdata:/var/lib/db -v /cache ",
+		}},
+		{"compose", []string{
+			"      - \"dbdata:/var/lib/db\"\n      - \"/cache\"\n",
+			"volumes:\n  dbdata:\n    name: dbdata\n    driver: local\n    driver_opts:\n",
+			"    labels:\n      backup: daily\n",
+		}},
+		{"quadlet", []string{
+			"Volume=dbdata.volume:/var/lib/db\nVolume=/cache\n",
+			"[Volume]\nVolumeName=dbdata\nDevice=:/data\nOptions=addr=10.0.0.1\nType=nfs\nLabel=backup=daily\n",
+		}},
+		{"terraform", []string{
+			"resource \"docker_volume\" \"dbdata\" {\n  name = \"dbdata\"\n  driver = \"local\"\n",
+			"    volume_name    = docker_volume.dbdata.name\n    container_path = \"/var/lib/db\"\n",
+			"  volumes {\n    container_path = \"/cache\"\n  }\n",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			opts := &ExportOptions{Format: tt.format, SingleFile: true, Volumes: map[string]volume.Volume{"dbdata": dbdataVolume()}}
+			out := exportString(t, []types.ContainerJSON{volumeContainer()}, opts)
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("missing %q in:\n%s", want, out)
+				}
+			}
+			// 不使用宿主机上的数据目录，匿名卷也不导出名称
+			for _, unwanted := range []string{"/var/lib/docker/volumes", anonymousVolume, "com.docker.compose.volume"} {
+				if strings.Contains(out, unwanted) {
+					t.Errorf("unexpected %q in:\n%s", unwanted, out)
+				}
+			}
+		})
+	}
+}
+
+func TestShortMountOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		mount types.MountPoint
+		want  string
+	}{
+		{"read write bind", types.MountPoint{Type: "bind", Source: "/src", Destination: "/dst", RW: true, Propagation: "rprivate"}, "/src:/dst"},
+		{"read only with selinux label", types.MountPoint{Type: "bind", Source: "/src", Destination: "/dst", Mode: "ro,Z"}, "/src:/dst:ro,Z"},
+		// Mode 中的 rw 以 RW 为准
+		{"propagation", types.MountPoint{Type: "bind", Source: "/src", Destination: "/dst", RW: true, Mode: "rw,z", Propagation: "rshared"}, "/src:/dst:z,rshared"},
+		{"volume nocopy", types.MountPoint{Type: "volume", Name: "data", Destination: "/data", RW: true, Mode: "nocopy"}, "data:/data:nocopy"},
+		{"anonymous read only", types.MountPoint{Type: "volume", Name: anonymousVolume, Destination: "/cache"}, "/cache:ro"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shortMountSpec(tt.mount); got != tt.want {
+				t.Errorf("spec = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// mountContainer 返回通过 -v 和 --mount 挂载的容器，--mount 的选项无法用 -v 表示
+func mountContainer() types.ContainerJSON {
+	web := testContainer("aaaaaaaaaaaa1111", "web")
+	web.Mounts = []types.MountPoint{
+		{Type: "bind", Source: "/etc/web", Destination: "/etc/nginx", Mode: "ro,Z", Propagation: "rprivate"},
+		{Type: "bind", Source: "/srv", Destination: "/srv", RW: true, Propagation: "rslave"},
+		{Type: "volume", Name: "data", Destination: "/data", Driver: "local", RW: true},
+		{Type: "tmpfs", Destination: "/tmp", RW: true},
+	}
+	web.HostConfig.Mounts = []mount.Mount{
+		{Type: "volume", Source: "data", Target: "/data", VolumeOptions: &mount.VolumeOptions{NoCopy: true}},
+		{Type: "tmpfs", Target: "/tmp", TmpfsOptions: &mount.TmpfsOptions{SizeBytes: 64 << 20, Mode: 01777}},
+	}
+	return web
+}
+
+func TestMountsExport(t *testing.T) {
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"command", []string{
+			"-v /etc/web:/etc/nginx:ro,Z -v /srv:/srv:rslave --mount type=volume,source=data,target=/data,volume-nocopy --mount type=tmpfs,target=/tmp,tmpfs-size=64m,tmpfs-mode=1777 ",
+		}},
+		{"compose", []string{
+			"      - \"/etc/web:/etc/nginx:ro,Z\"\n      - \"/srv:/srv:rslave\"\n",
+			"      - type: volume\n        source: data\n        target: /data\n        volume:\n          nocopy: true\n",
+			"      - type: tmpfs\n        target: /tmp\n        tmpfs:\n          size: 64m\n          mode: 0o1777\n",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			out := exportString(t, []types.ContainerJSON{mountContainer()}, &ExportOptions{Format: tt.format, SingleFile: true, External: true})
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("missing %q in:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestMountsRoundTrip(t *testing.T) {
+	for _, format := range []string{"command", "compose"} {
+		t.Run(format, func(t *testing.T) {
+			out := exportString(t, []types.ContainerJSON{mountContainer()}, &ExportOptions{Format: format, SingleFile: true, External: true})
+			var specs []ContainerSpec
+			var err error
+			if format == "command" {
+				specs, err = loadTestScript(t, out)
+			} else {
+				specs, err = LoadCompose(writeTestFile(t, t.TempDir(), "docker-compose.yml", out))
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			hostConfig := specs[0].HostConfig
+			if want := []string{"/etc/web:/etc/nginx:ro,Z", "/srv:/srv:rslave"}; !reflect.DeepEqual(hostConfig.Binds, want) {
+				t.Errorf("binds = %q, want %q", hostConfig.Binds, want)
+			}
+			mounts := make(map[string]mount.Mount)
+			for _, m := range hostConfig.Mounts {
+				mounts[m.Target] = m
+			}
+			if m := mounts["/data"]; m.VolumeOptions == nil || !m.VolumeOptions.NoCopy || m.Source != "data" {
+				t.Errorf("/data mount = %+v, want a nocopy volume", m)
+			}
+			if m := mounts["/tmp"]; m.TmpfsOptions == nil || m.TmpfsOptions.SizeBytes != 64<<20 || m.TmpfsOptions.Mode != 01777 {
+				t.Errorf("/tmp mount = %+v, want a 64m tmpfs with mode 1777", m)
+			}
+		})
+	}
+}